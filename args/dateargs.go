@@ -21,6 +21,11 @@ func (da *DateVar) Set(input string) error {
 	return err
 }
 
+// Get flag.Getter implementation
+func (da *DateVar) Get() interface{} {
+	return time.Time(*da)
+}
+
 // DurationVar structure
 type DurationVar time.Duration
 
@@ -37,3 +42,8 @@ func (da *DurationVar) Set(input string) error {
 	}
 	return err
 }
+
+// Get flag.Getter implementation
+func (da *DurationVar) Get() interface{} {
+	return time.Duration(*da)
+}
@@ -21,6 +21,11 @@ func (la *ListVar) Set(input string) error {
 	return nil
 }
 
+// Get flag.Getter interface implementation
+func (la *ListVar) Get() interface{} {
+	return []string(*la)
+}
+
 type ListIntVar []int
 
 // String interface implementation
@@ -41,3 +46,8 @@ func (lia *ListIntVar) Set(input string) error {
 	}
 	return nil
 }
+
+// Get flag.Getter interface implementation
+func (lia *ListIntVar) Get() interface{} {
+	return []int(*lia)
+}
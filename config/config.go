@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"reflect"
+	"strings"
 	"unsafe"
 
 	"github.com/0xrawsec/golang-utils/log"
@@ -32,6 +34,123 @@ func getRequiredError(key, ofType string, err error) {
 	configErrorf("Cannot get mandatory parameter %s as %s: %s ", key, ofType, err)
 }
 
+// PathSep separates the keys of nested configurations in a path
+const PathSep = "."
+
+// join appends a key to a key path
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + PathSep + key
+}
+
+// toMap returns the map behind a nested configuration value
+func toMap(v Value) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case Config:
+		return *(*map[string]interface{})(unsafe.Pointer(&m)), true
+	}
+	return nil, false
+}
+
+// fromMap returns the Config view of a nested configuration map
+func fromMap(m map[string]interface{}) Config {
+	return *(*Config)(unsafe.Pointer(&m))
+}
+
+// lookupPath walks nested configurations to find the value at path
+func lookupPath(c Config, path string) (Value, bool) {
+	var cur Value = c
+	for _, key := range strings.Split(path, PathSep) {
+		m, ok := toMap(cur)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// setPath sets value at path, creating nested configurations when needed
+func setPath(c Config, path string, value Value) {
+	keys := strings.Split(path, PathSep)
+	m, _ := toMap(c)
+	for _, key := range keys[:len(keys)-1] {
+		sub, ok := toMap(m[key])
+		if !ok {
+			sub = make(map[string]interface{})
+			m[key] = sub
+		}
+		m = sub
+	}
+	m[keys[len(keys)-1]] = value
+}
+
+// copyValue deep copies nested configurations and slices
+func copyValue(v Value) Value {
+	if m, ok := toMap(v); ok {
+		out := make(map[string]interface{}, len(m))
+		for k, e := range m {
+			out[k] = copyValue(e)
+		}
+		return out
+	}
+	if s, ok := v.([]interface{}); ok {
+		out := make([]interface{}, len(s))
+		for i, e := range s {
+			out[i] = copyValue(e)
+		}
+		return out
+	}
+	return v
+}
+
+// normalize converts typed slices into []interface{} so that values coming
+// from Go code are stored the same way as values coming from json
+func normalize(v Value) Value {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = normalize(rv.Index(i).Interface())
+		}
+		return out
+	}
+	return v
+}
+
+// merge deep merges src into dst, nested configurations are merged key by key
+// while any other value in src replaces the one in dst
+func merge(dst, src Config) {
+	d, _ := toMap(dst)
+	for key, sv := range src {
+		if sm, ok := toMap(sv); ok {
+			if dm, ok := toMap(d[key]); ok {
+				merge(fromMap(dm), fromMap(sm))
+				continue
+			}
+		}
+		d[key] = copyValue(sv)
+	}
+}
+
+// leaves calls fn for every value of c which is not a nested configuration
+func leaves(c Config, prefix string, fn func(path string, v Value)) {
+	for key, v := range c {
+		path := join(prefix, key)
+		if m, ok := toMap(v); ok && len(m) > 0 {
+			leaves(fromMap(m), path, fn)
+			continue
+		}
+		fn(path, v)
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 // Loads : loads a configuration structure from a data buffer
@@ -56,6 +175,43 @@ func Load(path string) (c Config, err error) {
 	return Loads([]byte(data))
 }
 
+// LoadsResolved loads a configuration structure from a data buffer like
+// Loads and resolves its include directives, file references and
+// interpolations (c.f. IncludeKey, FilePrefix and interpolate). Includes and
+// file references are relative to dir. Resolution reads files so it must not
+// be used on untrusted data.
+func LoadsResolved(data []byte, dir string) (c Config, err error) {
+	if c, err = Loads(data); err != nil {
+		return
+	}
+	if c, err = resolveFile(c, "", dir, nil, nil); err != nil {
+		return
+	}
+	err = interpolate(c)
+	return
+}
+
+// LoadResolved loads a configuration file like Load and resolves its include
+// directives, file references and interpolations, relative to the file
+// (c.f. LoadsResolved)
+func LoadResolved(path string) (c Config, err error) {
+	return loadResolved(path, nil)
+}
+
+// loadResolved is LoadResolved recording the stamps of the files read in
+// stamps if not nil
+func loadResolved(path string, stamps map[string]fileStamp) (c Config, err error) {
+	if c, err = loadFile(path, nil, stamps); err != nil {
+		return
+	}
+	if err = interpolate(c); err != nil {
+		if re, ok := err.(*ResolveError); ok {
+			re.File = path
+		}
+	}
+	return
+}
+
 // Dumps : Dumps Config structure into a byte slice
 // return ([]byte, error) : byte slice and error code
 func (c *Config) Dumps() (dump []byte, err error) {
@@ -290,3 +446,25 @@ func (c *Config) HasKey(key string) bool {
 	_, ok := (*c)[key]
 	return ok
 }
+
+// Copy returns a deep copy of the configuration
+func (c *Config) Copy() Config {
+	m, _ := toMap(copyValue(*c))
+	return fromMap(m)
+}
+
+// Merge deep merges other into the configuration. Nested configurations are
+// merged key by key while slices and scalar values of other replace the
+// existing ones.
+func (c *Config) Merge(other Config) {
+	if *c == nil {
+		*c = make(Config)
+	}
+	merge(*c, other)
+}
+
+// Diff returns the key paths added, removed and changed in other compared
+// to the configuration
+func (c *Config) Diff(other Config) Diff {
+	return diff(*c, other)
+}
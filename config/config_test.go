@@ -1,6 +1,7 @@
 package config
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -72,3 +73,40 @@ func TestLoadJson(t *testing.T) {
 	t.Log(s)
 
 }
+
+func TestMergeDiff(t *testing.T) {
+	base, err := Loads([]byte(`{"a": 1, "sub": {"b": "foo", "c": [1, 2], "d": {"e": true}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := Loads([]byte(`{"f": "new", "sub": {"c": [3], "d": {"g": 4}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merged := base.Copy()
+	merged.Merge(other)
+	expected, err := Loads([]byte(`{"a": 1, "f": "new", "sub": {"b": "foo", "c": [3], "d": {"e": true, "g": 4}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := merged.Diff(expected); !d.Empty() {
+		t.Errorf("Bad merge: %v", d)
+	}
+
+	d := base.Diff(merged)
+	if !reflect.DeepEqual(d.Added, []string{"f", "sub.d.g"}) ||
+		len(d.Removed) != 0 ||
+		!reflect.DeepEqual(d.Changed, []string{"sub.c"}) {
+		t.Errorf("Bad diff: %+v", d)
+	}
+
+	// base must not have been modified by merge
+	if d := base.Diff(base.Copy()); !d.Empty() {
+		t.Error("Copy must be equal")
+	}
+	sub := base.GetRequiredSubConfig("sub")
+	if _, err := sub.GetSubConfig("d"); err != nil || len(sub["d"].(map[string]interface{})) != 1 {
+		t.Error("Merge must not modify merged configuration")
+	}
+}
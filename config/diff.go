@@ -0,0 +1,51 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Diff lists the key paths differing between two configurations
+type Diff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// Empty returns true if the Diff does not contain any change
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// diff computes the Diff needed to go from old to new
+func diff(old, new Config) (d Diff) {
+	diffMaps(&d, "", old, new)
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return
+}
+
+func diffMaps(d *Diff, prefix string, old, new Config) {
+	for key, ov := range old {
+		path := join(prefix, key)
+		nv, ok := new[key]
+		if !ok {
+			d.Removed = append(d.Removed, path)
+			continue
+		}
+		om, oIsMap := toMap(ov)
+		nm, nIsMap := toMap(nv)
+		switch {
+		case oIsMap && nIsMap:
+			diffMaps(d, path, fromMap(om), fromMap(nm))
+		case !reflect.DeepEqual(ov, nv):
+			d.Changed = append(d.Changed, path)
+		}
+	}
+	for key := range new {
+		if _, ok := old[key]; !ok {
+			d.Added = append(d.Added, join(prefix, key))
+		}
+	}
+}
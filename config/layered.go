@@ -0,0 +1,175 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+)
+
+// Source identifies the layer a configuration value comes from
+type Source int
+
+const (
+	// SourceNone is reported for values not supplied by any layer
+	SourceNone Source = iota
+	// SourceFile layer is made of configuration files
+	SourceFile
+	// SourceEnv layer is made of environment variables
+	SourceEnv
+	// SourceFlag layer is made of command line flags
+	SourceFlag
+)
+
+var (
+	sourceNames = [...]string{"none", "file", "env", "flag"}
+)
+
+// String implements fmt.Stringer interface
+func (s Source) String() string {
+	if s < 0 || int(s) >= len(sourceNames) {
+		return "unknown"
+	}
+	return sourceNames[s]
+}
+
+// EnvPathSep separates the keys of nested configurations in environment
+// variable names
+const EnvPathSep = "__"
+
+// Layered is a configuration built from several layers of sources. Layers
+// are merged in Source order so environment variables override configuration
+// files and command line flags override both.
+type Layered struct {
+	layers [SourceFlag + 1]Config
+}
+
+// NewLayered creates a new empty Layered configuration
+func NewLayered() *Layered {
+	l := &Layered{}
+	for i := range l.layers {
+		l.layers[i] = make(Config)
+	}
+	return l
+}
+
+// LoadFile loads a configuration file into the file layer. Several files can
+// be loaded, the last one overriding the previous ones
+// @path : path where the configuration is stored as a json file
+func (l *Layered) LoadFile(path string) error {
+	c, err := Load(path)
+	if err != nil {
+		return err
+	}
+	l.layers[SourceFile].Merge(c)
+	return nil
+}
+
+// LoadEnv loads into the env layer the environment variables starting with
+// prefix followed by an underscore. The remaining of the variable name is
+// lowercased and split on EnvPathSep to build the key path, so that
+// APP_OUTPUT__PATH overrides output.path when prefix is APP. A path element
+// matches an existing key in which dashes are used instead of underscores.
+// Values are decoded as json, unless the value they override is a string.
+func (l *Layered) LoadEnv(prefix string) {
+	prefix = strings.ToUpper(prefix) + "_"
+	lower := l.below(SourceEnv)
+	for _, kv := range os.Environ() {
+		sp := strings.SplitN(kv, "=", 2)
+		if len(sp) != 2 || !strings.HasPrefix(sp[0], prefix) {
+			continue
+		}
+		path := envPath(lower, strings.TrimPrefix(sp[0], prefix))
+		setPath(l.layers[SourceEnv], path, envValue(lower, path, sp[1]))
+	}
+}
+
+// LoadFlags loads into the flag layer the flags of fs which have been set on
+// the command line. Flag names are used as key paths, so a flag named
+// output.path overrides the output.path key. The typed value is taken for
+// flags implementing flag.Getter (like the ones of the args package), the
+// string representation is used otherwise.
+func (l *Layered) LoadFlags(fs *flag.FlagSet) {
+	fs.Visit(func(f *flag.Flag) {
+		var value Value = f.Value.String()
+		if g, ok := f.Value.(flag.Getter); ok {
+			value = normalize(g.Get())
+		}
+		setPath(l.layers[SourceFlag], f.Name, value)
+	})
+}
+
+// Set sets value at key path in the layer of the given source
+func (l *Layered) Set(src Source, path string, value Value) {
+	setPath(l.layers[src], path, normalize(value))
+}
+
+// Config returns the configuration obtained by merging all the layers
+func (l *Layered) Config() Config {
+	return l.below(SourceFlag + 1)
+}
+
+// Origin returns the source of the layer supplying the value at key path
+func (l *Layered) Origin(path string) Source {
+	for src := SourceFlag; src > SourceNone; src-- {
+		if _, ok := lookupPath(l.layers[src], path); ok {
+			return src
+		}
+	}
+	return SourceNone
+}
+
+// Origins returns the source of every value of the merged configuration,
+// indexed by key path
+func (l *Layered) Origins() map[string]Source {
+	origins := make(map[string]Source)
+	leaves(l.Config(), "", func(path string, v Value) {
+		origins[path] = l.Origin(path)
+	})
+	return origins
+}
+
+// below merges all the layers with a lower priority than src
+func (l *Layered) below(src Source) Config {
+	c := make(Config)
+	for i := SourceNone; i < src; i++ {
+		c.Merge(l.layers[i])
+	}
+	return c
+}
+
+// envPath converts an environment variable name (prefix stripped) into a key
+// path, matching existing keys of c when possible
+func envPath(c Config, name string) string {
+	var cur Value = c
+	keys := strings.Split(strings.ToLower(name), strings.ToLower(EnvPathSep))
+	for i, key := range keys {
+		m, ok := toMap(cur)
+		if !ok {
+			break
+		}
+		for existing := range m {
+			if strings.Replace(existing, "-", "_", -1) == key {
+				keys[i] = existing
+				break
+			}
+		}
+		cur = m[keys[i]]
+	}
+	return strings.Join(keys, PathSep)
+}
+
+// envValue decodes an environment variable value according to the value it
+// overrides at path in c
+func envValue(c Config, path, raw string) Value {
+	if old, ok := lookupPath(c, path); ok {
+		if _, isString := old.(string); isString {
+			return raw
+		}
+	}
+	var value Value
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return raw
+	}
+	return value
+}
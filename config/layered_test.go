@@ -0,0 +1,74 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/0xrawsec/golang-utils/args"
+)
+
+func TestLayered(t *testing.T) {
+	var list args.ListVar
+	var timeout args.DurationVar
+
+	l := NewLayered()
+	if err := l.LoadFile(configpath); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("APP_LOG_SEARCH__HOST", "localhost")
+	os.Setenv("APP_MISP__PORT", "8443")
+	os.Setenv("APP_NOTIFIER_EMAIL", "bar@test.com")
+	defer os.Unsetenv("APP_LOG_SEARCH__HOST")
+	defer os.Unsetenv("APP_MISP__PORT")
+	defer os.Unsetenv("APP_NOTIFIER_EMAIL")
+	l.LoadEnv("app")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("misp.host", "", "misp host")
+	fs.String("misp.protocol", "", "never set")
+	fs.Var(&list, "notification-recipients", "recipients")
+	fs.Var(&timeout, "timeout", "timeout")
+	if err := fs.Parse([]string{"-misp.host", "127.0.0.1", "-notification-recipients", "a@b.com,c@d.com", "-timeout", "2s"}); err != nil {
+		t.Fatal(err)
+	}
+	l.LoadFlags(fs)
+
+	c := l.Config()
+	ls := c.GetRequiredSubConfig("log-search")
+	if ls.GetRequiredString("host") != "localhost" || ls.GetRequiredString("protocol") != "https" {
+		t.Errorf("Bad log-search config: %v", ls)
+	}
+	misp := c.GetRequiredSubConfig("misp")
+	if misp.GetRequiredString("host") != "127.0.0.1" || misp.GetRequiredInt64("port") != 8443 {
+		t.Errorf("Bad misp config: %v", misp)
+	}
+	if misp.GetRequiredString("protocol") != "https" {
+		t.Error("Unset flag must not override file value")
+	}
+	if rcpt := c.GetRequiredStringSlice("notification-recipients"); len(rcpt) != 2 {
+		t.Errorf("Bad recipients: %v", rcpt)
+	}
+	if v, _ := c.Get("timeout"); v != 2*time.Second {
+		t.Errorf("Bad timeout: %v", v)
+	}
+
+	expected := map[string]Source{
+		"misp.host":               SourceFlag,
+		"misp.port":               SourceEnv,
+		"misp.api-key":            SourceFile,
+		"log-search.host":         SourceEnv,
+		"notifier-email":          SourceEnv,
+		"notification-recipients": SourceFlag,
+		"timeout":                 SourceFlag,
+		"does.not.exist":          SourceNone,
+	}
+	for path, src := range expected {
+		if o := l.Origin(path); o != src {
+			t.Errorf("Bad origin for %s: %s instead of %s", path, o, src)
+		}
+	}
+	t.Log(l.Origins())
+}
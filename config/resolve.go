@@ -0,0 +1,317 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// IncludeKey is the key of include directives. Its value is either a path
+	// or a list of paths of configuration files, relative to the including
+	// file, merged into the object holding the directive. The keys of that
+	// object take precedence over the included ones.
+	IncludeKey = "$include"
+	// FilePrefix prefixes string values to be replaced by the content of a
+	// file, relative to the configuration file, stripped of trailing newlines
+	FilePrefix = "file:"
+)
+
+var (
+	// ErrIncludeCycle is returned when configuration files include each other
+	ErrIncludeCycle = errors.New("Include cycle")
+	// ErrInterpolationCycle is returned when values reference each other
+	ErrInterpolationCycle = errors.New("Interpolation cycle")
+	// ErrUndefinedVariable is returned when interpolating an unknown variable
+	ErrUndefinedVariable = errors.New("Undefined variable")
+	// ErrBadInterpolation is returned when an interpolation is not terminated
+	ErrBadInterpolation = errors.New("Unterminated interpolation")
+)
+
+// ResolveError is returned when includes, file references or interpolations
+// cannot be resolved at load time
+type ResolveError struct {
+	// File being loaded, empty if not loaded from a file
+	File string
+	// Path of the key being resolved
+	Path string
+	Err  error
+}
+
+// Error implements error interface
+func (e *ResolveError) Error() string {
+	location := e.Path
+	switch {
+	case e.File != "" && e.Path != "":
+		location = fmt.Sprintf("%s:%s", e.File, e.Path)
+	case e.File != "":
+		location = e.File
+	}
+	return fmt.Sprintf("Cannot resolve %s: %s", location, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ResolveError) Unwrap() error {
+	return e.Err
+}
+
+// fileValue holds the content of a file reference, excluded from interpolation
+type fileValue string
+
+// loadFile loads and resolves a configuration file, stack holding the
+// absolute paths of the files including it. The stamps of the files read are
+// recorded in stamps if not nil.
+func loadFile(path string, stack []string, stamps map[string]fileStamp) (c Config, err error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	// stamped before reading so that a concurrent change is not missed
+	if stamps != nil {
+		stamps[abs] = stamp(abs)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(data, &c); err != nil {
+		return
+	}
+	return resolveFile(c, path, filepath.Dir(abs), append(stack, abs), stamps)
+}
+
+// resolveFile resolves include directives and file references of c which
+// has been loaded from file located in dir
+func resolveFile(c Config, file, dir string, stack []string, stamps map[string]fileStamp) (Config, error) {
+	r := &fileResolver{file, dir, stack, stamps}
+	v, err := r.resolve("", c)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := toMap(v)
+	return fromMap(m), nil
+}
+
+type fileResolver struct {
+	file   string
+	dir    string
+	stack  []string
+	stamps map[string]fileStamp
+}
+
+func (r *fileResolver) errorf(path string, err error) error {
+	return &ResolveError{File: r.file, Path: path, Err: err}
+}
+
+func (r *fileResolver) abs(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(r.dir, path)
+}
+
+func (r *fileResolver) resolve(path string, v Value) (Value, error) {
+	switch t := v.(type) {
+	case string:
+		if strings.HasPrefix(t, FilePrefix) {
+			ref := r.abs(strings.TrimPrefix(t, FilePrefix))
+			if r.stamps != nil {
+				r.stamps[ref] = stamp(ref)
+			}
+			data, err := ioutil.ReadFile(ref)
+			if err != nil {
+				return nil, r.errorf(path, err)
+			}
+			return fileValue(strings.TrimRight(string(data), "\r\n")), nil
+		}
+	case []interface{}:
+		for i, e := range t {
+			var err error
+			if t[i], err = r.resolve(fmt.Sprintf("%s[%d]", path, i), e); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		if m, ok := toMap(v); ok {
+			return r.resolveMap(path, m)
+		}
+	}
+	return v, nil
+}
+
+func (r *fileResolver) resolveMap(path string, m map[string]interface{}) (Value, error) {
+	for key, e := range m {
+		if key == IncludeKey {
+			continue
+		}
+		resolved, err := r.resolve(join(path, key), e)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = resolved
+	}
+
+	inc, ok := m[IncludeKey]
+	if !ok {
+		return m, nil
+	}
+	delete(m, IncludeKey)
+
+	var includes []string
+	switch t := inc.(type) {
+	case string:
+		includes = []string{t}
+	case []interface{}:
+		for _, e := range t {
+			s, ok := e.(string)
+			if !ok {
+				return nil, r.errorf(join(path, IncludeKey), fmt.Errorf("Wrong type for include (Type:%T Expecting:%T)", e, s))
+			}
+			includes = append(includes, s)
+		}
+	default:
+		return nil, r.errorf(join(path, IncludeKey), fmt.Errorf("Wrong type for include (Type:%T Expecting:%T)", inc, ""))
+	}
+
+	out := make(Config)
+	for _, include := range includes {
+		include = r.abs(include)
+		for i, f := range r.stack {
+			if f == include {
+				chain := strings.Join(append(r.stack[i:], include), " -> ")
+				return nil, r.errorf(join(path, IncludeKey), fmt.Errorf("%w: %s", ErrIncludeCycle, chain))
+			}
+		}
+		c, err := loadFile(include, r.stack, r.stamps)
+		if err != nil {
+			if _, ok := err.(*ResolveError); ok {
+				return nil, err
+			}
+			return nil, r.errorf(join(path, IncludeKey), err)
+		}
+		out.Merge(c)
+	}
+	// keys of the including object take precedence
+	out.Merge(fromMap(m))
+	om, _ := toMap(out)
+	return om, nil
+}
+
+// interpolate replaces ${name} patterns in the string values of c, name being
+// either the path of another key of c or an environment variable. A string
+// made of a single pattern referencing a key takes the value of that key.
+// Use $${ to write a literal ${.
+func interpolate(c Config) error {
+	i := &interpolator{root: c, state: make(map[string]bool)}
+	_, err := i.resolve("", c)
+	return err
+}
+
+type interpolator struct {
+	root Config
+	// state is false for the paths being resolved, true for resolved ones
+	state map[string]bool
+}
+
+func (i *interpolator) resolve(path string, v Value) (Value, error) {
+	switch t := v.(type) {
+	case fileValue:
+		return string(t), nil
+	case string:
+		return i.expand(path, t)
+	case []interface{}:
+		for k, e := range t {
+			var err error
+			if t[k], err = i.resolve(fmt.Sprintf("%s[%d]", path, k), e); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		if m, ok := toMap(v); ok {
+			for key, e := range m {
+				resolved, err := i.resolvePath(join(path, key), e)
+				if err != nil {
+					return nil, err
+				}
+				m[key] = resolved
+			}
+		}
+	}
+	return v, nil
+}
+
+// resolvePath resolves the value at path, keeping track of the paths
+// being resolved to detect cycles
+func (i *interpolator) resolvePath(path string, v Value) (Value, error) {
+	if done, ok := i.state[path]; ok {
+		if !done {
+			return nil, &ResolveError{Path: path, Err: ErrInterpolationCycle}
+		}
+		return v, nil
+	}
+	i.state[path] = false
+	resolved, err := i.resolve(path, v)
+	if err != nil {
+		return nil, err
+	}
+	i.state[path] = true
+	return resolved, nil
+}
+
+func (i *interpolator) expand(path, s string) (Value, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	out := new(strings.Builder)
+	for k := 0; k < len(s); {
+		switch {
+		case strings.HasPrefix(s[k:], "$${"):
+			out.WriteString("${")
+			k += 3
+		case strings.HasPrefix(s[k:], "${"):
+			end := strings.IndexByte(s[k:], '}')
+			if end == -1 {
+				return nil, &ResolveError{Path: path, Err: ErrBadInterpolation}
+			}
+			name := s[k+2 : k+end]
+			value, err := i.lookup(path, name)
+			if err != nil {
+				return nil, err
+			}
+			// a single pattern keeps the type of the value referenced
+			if k == 0 && end == len(s)-1 {
+				return value, nil
+			}
+			if str, ok := value.(string); ok {
+				out.WriteString(str)
+			} else {
+				fmt.Fprintf(out, "%v", value)
+			}
+			k += end + 1
+		default:
+			out.WriteByte(s[k])
+			k++
+		}
+	}
+	return out.String(), nil
+}
+
+func (i *interpolator) lookup(path, name string) (Value, error) {
+	if v, ok := lookupPath(i.root, name); ok {
+		resolved, err := i.resolvePath(name, v)
+		if err != nil {
+			return nil, err
+		}
+		setPath(i.root, name, resolved)
+		return resolved, nil
+	}
+	if v, ok := os.LookupEnv(name); ok {
+		return v, nil
+	}
+	return nil, &ResolveError{Path: path, Err: fmt.Errorf("%w: %s", ErrUndefinedVariable, name)}
+}
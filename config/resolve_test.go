@@ -0,0 +1,85 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestInclude(t *testing.T) {
+	c, err := LoadResolved("./test/include/main.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(c)
+
+	if c.GetRequiredString("name") != "main" {
+		t.Error("Including file must take precedence")
+	}
+	log := c.GetRequiredSubConfig("log")
+	if log.GetRequiredString("level") != "info" {
+		t.Error("Missing included value")
+	}
+
+	db := c.GetRequiredSubConfig("db")
+	if db.GetRequiredString("password") != "p@ss${word}" {
+		t.Errorf("Bad file reference: %q", db.GetRequiredString("password"))
+	}
+	if db.GetRequiredString("ca") != "CERT" {
+		t.Error("File reference must be relative to included file")
+	}
+	if url := db.GetRequiredString("url"); url != "postgres://admin@db.local:5432/main" {
+		t.Errorf("Bad interpolation: %s", url)
+	}
+	if c.GetRequiredString("home") != os.Getenv("HOME") {
+		t.Error("Bad environment interpolation")
+	}
+	if c.GetRequiredInt64("port") != 5432 {
+		t.Error("Single interpolation must keep value type")
+	}
+	if c.GetRequiredString("literal") != "${HOME}" {
+		t.Error("Bad escaping")
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	_, err := LoadResolved("./test/include/cycle1.json")
+	if !errors.Is(err, ErrIncludeCycle) {
+		t.Fatalf("Expecting include cycle error: %v", err)
+	}
+	t.Log(err)
+}
+
+func TestInterpolationErrors(t *testing.T) {
+	for data, expected := range map[string]error{
+		`{"a": "${b}", "b": "${a}"}`:                    ErrInterpolationCycle,
+		`{"a": {"b": "${a}"}}`:                          ErrInterpolationCycle,
+		`{"a": "${THIS_VARIABLE_IS_NOT_DEFINED}"}`:      ErrUndefinedVariable,
+		`{"a": "${unterminated"}`:                       ErrBadInterpolation,
+		`{"a": "file:./test/this/file/does/not/exist"}`: os.ErrNotExist,
+	} {
+		_, err := LoadsResolved([]byte(data), ".")
+		if !errors.Is(err, expected) {
+			t.Errorf("Expecting %v for %s: %v", expected, data, err)
+		}
+		t.Log(err)
+	}
+}
+
+func TestLoadIsRaw(t *testing.T) {
+	c, err := Load("./test/include/main.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.HasKey(IncludeKey) {
+		t.Error("Load must not resolve includes")
+	}
+
+	data := `{"url": "file:///etc/passwd", "tpl": "${HOME}"}`
+	if c, err = Loads([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if c.GetRequiredString("url") != "file:///etc/passwd" || c.GetRequiredString("tpl") != "${HOME}" {
+		t.Errorf("Loads must keep raw values: %v", c)
+	}
+}
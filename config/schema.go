@@ -0,0 +1,400 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Type of a configuration value described by a Schema
+type Type string
+
+const (
+	// TypeAny matches any value
+	TypeAny Type = ""
+	// TypeString matches string values
+	TypeString Type = "string"
+	// TypeNumber matches any numeric value
+	TypeNumber Type = "number"
+	// TypeInteger matches numeric values without fractional part
+	TypeInteger Type = "integer"
+	// TypeBoolean matches boolean values
+	TypeBoolean Type = "boolean"
+	// TypeArray matches array values
+	TypeArray Type = "array"
+	// TypeObject matches nested configurations
+	TypeObject Type = "object"
+)
+
+// Schema describes the expected shape of a configuration value. It can be
+// written in Go or loaded from a subset of JSON schema supporting the type,
+// description, default, minimum, maximum, enum, pattern, properties,
+// required, items and (boolean) additionalProperties keywords.
+type Schema struct {
+	Type        Type
+	Description string
+	// Required is only meaningful for properties of an object
+	Required bool
+	Default  Value
+	// Minimum and Maximum bound numeric values
+	Minimum *float64
+	Maximum *float64
+	Enum    []Value
+	// Pattern is a regular expression string values must match
+	Pattern string
+	// Properties describes the keys of an object
+	Properties map[string]*Schema
+	// Strict forbids keys of an object not described in Properties
+	Strict bool
+	// Items describes the elements of an array
+	Items *Schema
+}
+
+// Violation of a Schema found at a given key path
+type Violation struct {
+	Path    string
+	Message string
+}
+
+// Error implements error interface
+func (v Violation) Error() string {
+	if v.Path == "" {
+		return v.Message
+	}
+	return fmt.Sprintf("%s: %s", v.Path, v.Message)
+}
+
+// ValidationError gathers all the violations found while validating a value
+type ValidationError []Violation
+
+// Error implements error interface
+func (e ValidationError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, v := range e {
+		msgs = append(msgs, v.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Float64 returns a pointer to f, helper to set Schema bounds
+func Float64(f float64) *float64 {
+	return &f
+}
+
+// LoadsSchema loads a Schema from a JSON schema data buffer
+func LoadsSchema(data []byte) (s *Schema, err error) {
+	s = &Schema{}
+	if err = json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, s.check("")
+}
+
+// LoadSchema loads a Schema from a JSON schema file
+func LoadSchema(path string) (*Schema, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return LoadsSchema(data)
+}
+
+type jsonSchema struct {
+	Type                 Type               `json:"type,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Default              Value              `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Enum                 []Value            `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (s *Schema) UnmarshalJSON(data []byte) (err error) {
+	var js jsonSchema
+	if err = json.Unmarshal(data, &js); err != nil {
+		return
+	}
+	*s = Schema{
+		Type:        js.Type,
+		Description: js.Description,
+		Default:     js.Default,
+		Minimum:     js.Minimum,
+		Maximum:     js.Maximum,
+		Enum:        js.Enum,
+		Pattern:     js.Pattern,
+		Properties:  js.Properties,
+		Strict:      js.AdditionalProperties != nil && !*js.AdditionalProperties,
+		Items:       js.Items}
+	for _, key := range js.Required {
+		if p, ok := s.Properties[key]; ok {
+			p.Required = true
+			continue
+		}
+		return fmt.Errorf("Required property %s is not defined", key)
+	}
+	return
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	js := jsonSchema{
+		Type:        s.Type,
+		Description: s.Description,
+		Default:     s.Default,
+		Minimum:     s.Minimum,
+		Maximum:     s.Maximum,
+		Enum:        s.Enum,
+		Pattern:     s.Pattern,
+		Properties:  s.Properties,
+		Items:       s.Items}
+	for key, p := range s.Properties {
+		if p.Required {
+			js.Required = append(js.Required, key)
+		}
+	}
+	sort.Strings(js.Required)
+	if s.Strict {
+		js.AdditionalProperties = new(bool)
+	}
+	return json.Marshal(js)
+}
+
+// check controls the Schema is consistent
+func (s *Schema) check(path string) error {
+	switch s.Type {
+	case TypeAny, TypeString, TypeNumber, TypeInteger, TypeBoolean, TypeArray, TypeObject:
+	default:
+		return Violation{path, fmt.Sprintf("unknown type %q in schema", s.Type)}
+	}
+	if s.Pattern != "" {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return Violation{path, fmt.Sprintf("bad pattern in schema: %s", err)}
+		}
+	}
+	for key, p := range s.Properties {
+		if err := p.check(join(path, key)); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.check(path + "[]")
+	}
+	return nil
+}
+
+// Validate validates a configuration against the Schema and returns a
+// ValidationError listing all the violations found
+func (s *Schema) Validate(c Config) error {
+	var violations ValidationError
+	s.validate("", c, &violations)
+	if len(violations) > 0 {
+		return violations
+	}
+	return nil
+}
+
+func (s *Schema) validate(path string, v Value, violations *ValidationError) {
+	report := func(format string, i ...interface{}) {
+		*violations = append(*violations, Violation{path, fmt.Sprintf(format, i...)})
+	}
+
+	if !s.hasType(v) {
+		report("wrong type %T, expecting %s", v, s.Type)
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if equalValues(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			report("value %v not in %v", v, s.Enum)
+		}
+	}
+
+	if f, ok := toFloat(v); ok {
+		if s.Minimum != nil && f < *s.Minimum {
+			report("value %v lower than minimum %v", v, *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			report("value %v greater than maximum %v", v, *s.Maximum)
+		}
+	}
+
+	if str, ok := v.(string); ok && s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		switch {
+		case err != nil:
+			report("bad pattern in schema: %s", err)
+		case !re.MatchString(str):
+			report("value %q does not match %s", str, s.Pattern)
+		}
+	}
+
+	if m, ok := toMap(v); ok {
+		for _, key := range sortedKeys(s.Properties) {
+			p := s.Properties[key]
+			if sub, ok := m[key]; ok {
+				p.validate(join(path, key), sub, violations)
+			} else if p.Required {
+				*violations = append(*violations, Violation{join(path, key), "missing required key"})
+			}
+		}
+		if s.Strict {
+			for _, key := range sortedKeys(m) {
+				if _, ok := s.Properties[key]; !ok {
+					*violations = append(*violations, Violation{join(path, key), "unknown key"})
+				}
+			}
+		}
+	}
+
+	if rv := reflect.ValueOf(v); s.Items != nil && rv.Kind() == reflect.Slice {
+		for i := 0; i < rv.Len(); i++ {
+			s.Items.validate(fmt.Sprintf("%s[%d]", path, i), rv.Index(i).Interface(), violations)
+		}
+	}
+}
+
+func (s *Schema) hasType(v Value) bool {
+	switch s.Type {
+	case TypeString:
+		_, ok := v.(string)
+		return ok
+	case TypeNumber:
+		_, ok := toFloat(v)
+		return ok
+	case TypeInteger:
+		f, ok := toFloat(v)
+		return ok && f == math.Trunc(f)
+	case TypeBoolean:
+		_, ok := v.(bool)
+		return ok
+	case TypeArray:
+		return v != nil && reflect.TypeOf(v).Kind() == reflect.Slice
+	case TypeObject:
+		_, ok := toMap(v)
+		return ok
+	}
+	return true
+}
+
+// Doc returns the documentation of all the keys described by the Schema,
+// one key per line in key path order
+func (s *Schema) Doc() string {
+	b := new(strings.Builder)
+	s.WriteDoc(b)
+	return b.String()
+}
+
+// WriteDoc writes the documentation returned by Doc to w
+func (s *Schema) WriteDoc(w io.Writer) (err error) {
+	for _, key := range sortedKeys(s.Properties) {
+		if err = s.Properties[key].writeDoc(w, key); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (s *Schema) writeDoc(w io.Writer, path string) (err error) {
+	attrs := make([]string, 0)
+	switch {
+	case s.Type == TypeArray && s.Items != nil && s.Items.Type != TypeAny:
+		attrs = append(attrs, fmt.Sprintf("%s of %s", s.Type, s.Items.Type))
+	case s.Type != TypeAny:
+		attrs = append(attrs, string(s.Type))
+	}
+	if s.Required {
+		attrs = append(attrs, "required")
+	} else {
+		attrs = append(attrs, "optional")
+	}
+	if s.Default != nil {
+		def, _ := json.Marshal(s.Default)
+		attrs = append(attrs, fmt.Sprintf("default: %s", def))
+	}
+	if s.Minimum != nil {
+		attrs = append(attrs, fmt.Sprintf("minimum: %v", *s.Minimum))
+	}
+	if s.Maximum != nil {
+		attrs = append(attrs, fmt.Sprintf("maximum: %v", *s.Maximum))
+	}
+	if len(s.Enum) > 0 {
+		enum, _ := json.Marshal(s.Enum)
+		attrs = append(attrs, fmt.Sprintf("enum: %s", enum))
+	}
+	if s.Pattern != "" {
+		attrs = append(attrs, fmt.Sprintf("pattern: %s", s.Pattern))
+	}
+
+	line := fmt.Sprintf("%s (%s)", path, strings.Join(attrs, ", "))
+	if s.Description != "" {
+		line += ": " + s.Description
+	}
+	if _, err = fmt.Fprintln(w, line); err != nil {
+		return
+	}
+
+	for _, key := range sortedKeys(s.Properties) {
+		if err = s.Properties[key].writeDoc(w, join(path, key)); err != nil {
+			return
+		}
+	}
+	if s.Items != nil && (len(s.Items.Properties) > 0 || s.Items.Items != nil) {
+		return s.Items.writeDoc(w, path+"[]")
+	}
+	return
+}
+
+//////////////////////////////// Utils /////////////////////////////////////////
+
+// sortedKeys returns the sorted keys of any map indexed by strings
+func sortedKeys(m interface{}) []string {
+	rv := reflect.ValueOf(m)
+	keys := make([]string, 0, rv.Len())
+	for _, k := range rv.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// toFloat converts any numeric value to float64
+func toFloat(v Value) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// equalValues compares values, numbers being compared whatever their type
+func equalValues(a, b Value) bool {
+	fa, aok := toFloat(a)
+	fb, bok := toFloat(b)
+	if aok && bok {
+		return fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
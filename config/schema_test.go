@@ -0,0 +1,101 @@
+package config
+
+import (
+	"sort"
+	"testing"
+)
+
+var (
+	schemapath = "./test/schema.json"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	s, err := LoadSchema(schemapath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(configpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Validate(c); err != nil {
+		t.Errorf("Config should be valid: %s", err)
+	}
+
+	bad, err := Loads([]byte(`{
+		"misp": {"protocol": "ftp", "port": 70000.5, "api-key": 42, "api-url": "foo"},
+		"log-search": "localhost",
+		"notification-recipients": ["foo@bar.com", "foo"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Validate(bad)
+	if err == nil {
+		t.Fatal("Config should not be valid")
+	}
+	t.Log(err)
+	paths := make([]string, 0)
+	for _, v := range err.(ValidationError) {
+		paths = append(paths, v.Path)
+	}
+	sort.Strings(paths)
+	expected := []string{
+		"log-search",
+		"misp.api-key",
+		"misp.api-url",
+		"misp.host",
+		"misp.port",
+		"misp.protocol",
+		"notification-recipients[1]",
+		"notifier-email",
+	}
+	if len(paths) != len(expected) {
+		t.Fatalf("Unexpected violations: %v", paths)
+	}
+	for i := range paths {
+		if paths[i] != expected[i] {
+			t.Errorf("Unexpected violation %s, expecting %s", paths[i], expected[i])
+		}
+	}
+}
+
+func TestSchemaGo(t *testing.T) {
+	s := &Schema{
+		Type:   TypeObject,
+		Strict: true,
+		Properties: map[string]*Schema{
+			"workers": {Type: TypeInteger, Minimum: Float64(1), Default: 4},
+			"level":   {Type: TypeString, Enum: []Value{"debug", "info"}, Required: true},
+		},
+	}
+	c := Config{"workers": 0, "level": "info", "unknown": true}
+	err := s.Validate(c)
+	if err == nil || len(err.(ValidationError)) != 2 {
+		t.Errorf("Expecting two violations: %v", err)
+	}
+	c = Config{"workers": 8, "level": "debug"}
+	if err := s.Validate(c); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSchemaDoc(t *testing.T) {
+	s, err := LoadSchema(schemapath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log("\n" + s.Doc())
+
+	// schema must survive a json round trip
+	data, err := s.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := LoadsSchema(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Doc() != other.Doc() {
+		t.Errorf("Schema changed after json round trip:\n%s", other.Doc())
+	}
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// SyncedConfig is a thread safe configuration. Readers access immutable
+// snapshots while writers modify a copy of the configuration which then
+// replaces the current snapshot (copy-on-write).
+type SyncedConfig struct {
+	// serializes writers, readers never lock
+	sync.Mutex
+	snapshot atomic.Value
+}
+
+// NewSyncedConfig creates a new SyncedConfig initialized with a copy of c
+func NewSyncedConfig(c Config) *SyncedConfig {
+	s := &SyncedConfig{}
+	s.snapshot.Store(c.Copy())
+	return s
+}
+
+// Snapshot returns the current configuration. The snapshot is never modified
+// by SyncedConfig so it can be safely read while the SyncedConfig is being
+// updated. It must not be modified by the caller.
+func (s *SyncedConfig) Snapshot() Config {
+	if c, ok := s.snapshot.Load().(Config); ok {
+		return c
+	}
+	return Config{}
+}
+
+// Get the Value associated to a key in the current snapshot
+func (s *SyncedConfig) Get(key string) (Value, error) {
+	c := s.Snapshot()
+	return c.Get(key)
+}
+
+// HasKey returns true if the current snapshot has the given key
+func (s *SyncedConfig) HasKey(key string) bool {
+	c := s.Snapshot()
+	return c.HasKey(key)
+}
+
+// Update calls fn with a copy of the current snapshot. The copy, modified by
+// fn, becomes the new snapshot.
+func (s *SyncedConfig) Update(fn func(c Config)) {
+	s.Lock()
+	defer s.Unlock()
+	current := s.Snapshot()
+	c := current.Copy()
+	fn(c)
+	s.snapshot.Store(c)
+}
+
+// Set : set parameter identified by key with a Value
+func (s *SyncedConfig) Set(key string, value interface{}) {
+	s.Update(func(c Config) {
+		c.Set(key, value)
+	})
+}
+
+// Merge deep merges other into the configuration (c.f. Config.Merge)
+func (s *SyncedConfig) Merge(other Config) {
+	s.Update(func(c Config) {
+		c.Merge(other)
+	})
+}
+
+// Replace replaces the current snapshot by a copy of c and returns the
+// previous snapshot
+func (s *SyncedConfig) Replace(c Config) (old Config) {
+	s.Lock()
+	defer s.Unlock()
+	old = s.Snapshot()
+	s.snapshot.Store(c.Copy())
+	return
+}
+
+// Diff returns the differences between the current snapshot and other
+func (s *SyncedConfig) Diff(other Config) Diff {
+	c := s.Snapshot()
+	return c.Diff(other)
+}
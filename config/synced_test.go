@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestSyncedConfig(t *testing.T) {
+	wg := sync.WaitGroup{}
+	sc := NewSyncedConfig(Config{"sub": map[string]interface{}{"a": 1.0}})
+	snapshot := sc.Snapshot()
+
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for k := 0; k < 100; k++ {
+				sc.Set(fmt.Sprintf("key-%d-%d", i, k), k)
+				sc.Merge(Config{"sub": map[string]interface{}{fmt.Sprintf("%d", i): k}})
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for k := 0; k < 100; k++ {
+				c := sc.Snapshot()
+				sub := c.GetRequiredSubConfig("sub")
+				for range sub {
+				}
+				c.HasKey("key-0-0")
+			}
+		}()
+	}
+	wg.Wait()
+
+	c := sc.Snapshot()
+	if len(c) != 1001 {
+		t.Errorf("Bad config length: %d", len(c))
+	}
+	if len(snapshot) != 1 || len(snapshot["sub"].(map[string]interface{})) != 1 {
+		t.Error("Snapshot must not be modified")
+	}
+	if d := sc.Diff(snapshot); len(d.Removed) != 1010 {
+		t.Errorf("Bad diff: %d removed", len(d.Removed))
+	}
+
+	old := sc.Replace(snapshot)
+	if d := old.Diff(sc.Snapshot()); len(d.Removed) != 1010 {
+		t.Errorf("Bad diff after replace: %d removed", len(d.Removed))
+	}
+}
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/0xrawsec/golang-utils/log"
+)
+
+const (
+	// DefaultWatchInterval is the default polling interval of a Watcher
+	DefaultWatchInterval = time.Second
+)
+
+// Handler is called by a Watcher whenever a configuration has been reloaded
+// with the previous configuration, the new one and the keys which changed.
+// Handlers are called in the order of the reloads.
+type Handler func(old, new Config, diff Diff)
+
+// ErrorHandler is called by a Watcher whenever a reload fails
+type ErrorHandler func(err error)
+
+// fileStamp identifies a version of a file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// stamp returns the stamp of the file at path, the zero stamp if it cannot
+// be stat
+func stamp(path string) fileStamp {
+	if fi, err := os.Stat(path); err == nil {
+		return fileStamp{fi.ModTime(), fi.Size()}
+	}
+	return fileStamp{}
+}
+
+// Watcher polls a configuration file and reloads it on change. A reload
+// failing to load or validate the file keeps the last good configuration.
+type Watcher struct {
+	sync.RWMutex
+	// Interval between two checks of the configuration file
+	Interval time.Duration
+	// Loader used to load the configuration file, Load by default. When nil
+	// the file is loaded with LoadResolved and the files it includes or
+	// references are watched as well (c.f. NewResolvedWatcher).
+	Loader func(path string) (Config, error)
+	// Validate, if not nil, must succeed for a configuration to be applied
+	Validate func(Config) error
+
+	path        string
+	config      Config
+	stamps      map[string]fileStamp
+	handlers    []Handler
+	errHandlers []ErrorHandler
+	// done is closed to stop the polling routine which closes stopped
+	// when it terminates
+	done    chan bool
+	stopped chan bool
+	// reload serializes reloads so that an older configuration is never
+	// applied after a newer one
+	reload sync.Mutex
+}
+
+// NewWatcher creates a new Watcher on the configuration file at path. The
+// file is loaded a first time and an error is returned if it fails.
+func NewWatcher(path string) (w *Watcher, err error) {
+	return newWatcher(path, Load)
+}
+
+// NewResolvedWatcher creates a new Watcher on the configuration file at path
+// loaded with LoadResolved. The configuration is reloaded whenever the file
+// or any file it includes or references changes.
+func NewResolvedWatcher(path string) (w *Watcher, err error) {
+	return newWatcher(path, nil)
+}
+
+func newWatcher(path string, loader func(string) (Config, error)) (w *Watcher, err error) {
+	w = &Watcher{
+		Interval: DefaultWatchInterval,
+		Loader:   loader,
+		path:     path}
+	if w.config, w.stamps, err = w.load(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// load loads the configuration file and returns the stamps of the files it
+// has been loaded from
+func (w *Watcher) load() (c Config, stamps map[string]fileStamp, err error) {
+	stamps = make(map[string]fileStamp)
+	if w.Loader == nil {
+		c, err = loadResolved(w.path, stamps)
+		return
+	}
+	stamps[w.path] = stamp(w.path)
+	c, err = w.Loader(w.path)
+	return
+}
+
+// Path returns the path of the watched configuration file
+func (w *Watcher) Path() string {
+	return w.path
+}
+
+// Config returns the current configuration. It is replaced, never modified,
+// on reload so it must not be modified by the caller.
+func (w *Watcher) Config() Config {
+	w.RLock()
+	defer w.RUnlock()
+	return w.config
+}
+
+// Subscribe registers a Handler called on every successful reload
+func (w *Watcher) Subscribe(h Handler) {
+	w.Lock()
+	defer w.Unlock()
+	w.handlers = append(w.handlers, h)
+}
+
+// OnError registers an ErrorHandler called on every failed reload. Errors
+// are logged when no ErrorHandler is registered.
+func (w *Watcher) OnError(h ErrorHandler) {
+	w.Lock()
+	defer w.Unlock()
+	w.errHandlers = append(w.errHandlers, h)
+}
+
+// Changed returns true if the configuration file, or any file it has been
+// loaded from, changed since last load
+func (w *Watcher) Changed() bool {
+	w.RLock()
+	defer w.RUnlock()
+	for path, s := range w.stamps {
+		// a missing file has a zero stamp so it is reported only once
+		if cur := stamp(path); !cur.modTime.Equal(s.modTime) || cur.size != s.size {
+			return true
+		}
+	}
+	return false
+}
+
+// Reload reloads the configuration file and notifies the subscribers if
+// anything changed. On error the current configuration is kept and the
+// error is both reported to error handlers and returned. Reloads are
+// serialized and handlers are called before the next reload starts, so
+// handlers must not call Reload.
+func (w *Watcher) Reload() (err error) {
+	w.reload.Lock()
+	defer w.reload.Unlock()
+
+	new, stamps, err := w.load()
+	if err == nil && w.Validate != nil {
+		err = w.Validate(new)
+	}
+
+	w.Lock()
+	// we do not want to try reloading a faulty file over and over
+	w.stamps = stamps
+	if err != nil {
+		errHandlers := w.errHandlers
+		w.Unlock()
+		w.reportError(errHandlers, err)
+		return
+	}
+	old := w.config
+	w.config = new
+	handlers := w.handlers
+	w.Unlock()
+
+	if d := old.Diff(new); !d.Empty() {
+		for _, h := range handlers {
+			h(old, new, d)
+		}
+	}
+	return
+}
+
+func (w *Watcher) reportError(handlers []ErrorHandler, err error) {
+	if len(handlers) == 0 {
+		log.Errorf("Failed to reload configuration %s: %s", w.path, err)
+	}
+	for _, h := range handlers {
+		h(err)
+	}
+}
+
+// Start starts the routine polling the configuration file, it does nothing
+// if the routine is already running
+func (w *Watcher) Start() {
+	w.Lock()
+	defer w.Unlock()
+	if w.done != nil {
+		return
+	}
+	done, stopped := make(chan bool), make(chan bool)
+	w.done, w.stopped = done, stopped
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(w.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if w.Changed() {
+					w.Reload()
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the polling routine and waits for it to terminate
+func (w *Watcher) Stop() {
+	w.Lock()
+	done, stopped := w.done, w.stopped
+	w.done, w.stopped = nil, nil
+	w.Unlock()
+	if done != nil {
+		close(done)
+		// the routine takes the lock to reload so we must not hold it
+		<-stopped
+	}
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, path, data string, mtime time.Time) {
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// make sure modification is visible whatever the fs time resolution is
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-watcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.json")
+	now := time.Now()
+
+	writeConfig(t, path, `{"a": 1, "sub": {"b": "foo", "c": true}}`, now)
+	w, err := NewWatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Interval = 10 * time.Millisecond
+	w.Validate = func(c Config) error {
+		if !c.HasKey("a") {
+			return errors.New("missing a")
+		}
+		return nil
+	}
+
+	diffs := make(chan Diff, 10)
+	errs := make(chan error, 10)
+	w.Subscribe(func(old, new Config, d Diff) {
+		if old.GetRequiredInt64("a") != 1 {
+			t.Errorf("Bad old config: %v", old)
+		}
+		diffs <- d
+	})
+	w.OnError(func(err error) { errs <- err })
+	w.Start()
+	defer w.Stop()
+
+	// unparsable config
+	writeConfig(t, path, `{"a": `, now.Add(time.Second))
+	select {
+	case err := <-errs:
+		t.Logf("Expected error: %s", err)
+	case <-time.After(time.Second):
+		t.Fatal("Error not reported")
+	}
+
+	// invalid config
+	writeConfig(t, path, `{"b": 1}`, now.Add(2*time.Second))
+	select {
+	case err := <-errs:
+		t.Logf("Expected error: %s", err)
+	case <-time.After(time.Second):
+		t.Fatal("Error not reported")
+	}
+	if c := w.Config(); c.GetRequiredInt64("a") != 1 {
+		t.Error("Last good config should have been kept")
+	}
+
+	writeConfig(t, path, `{"a": 1, "sub": {"b": "bar", "d": 42}, "e": []}`, now.Add(3*time.Second))
+	select {
+	case d := <-diffs:
+		expected := Diff{
+			Added:   []string{"e", "sub.d"},
+			Removed: []string{"sub.c"},
+			Changed: []string{"sub.b"}}
+		if !reflect.DeepEqual(d, expected) {
+			t.Errorf("Unexpected diff: %+v", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Reload not notified")
+	}
+	c := w.Config()
+	if s := c.GetRequiredSubConfig("sub"); s.GetRequiredString("b") != "bar" {
+		t.Errorf("Config not reloaded: %v", w.Config())
+	}
+}
+
+func TestResolvedWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-watcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.json")
+	included := filepath.Join(dir, "included.json")
+	now := time.Now()
+
+	writeConfig(t, included, `{"b": "foo"}`, now)
+	writeConfig(t, path, `{"a": 1, "$include": "included.json"}`, now)
+	w, err := NewResolvedWatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Interval = 10 * time.Millisecond
+
+	diffs := make(chan Diff, 10)
+	w.Subscribe(func(old, new Config, d Diff) { diffs <- d })
+	w.Start()
+	defer w.Stop()
+
+	// only the included file changes
+	writeConfig(t, included, `{"b": "bar"}`, now.Add(time.Second))
+	select {
+	case d := <-diffs:
+		if !reflect.DeepEqual(d.Changed, []string{"b"}) {
+			t.Errorf("Unexpected diff: %+v", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Reload not notified")
+	}
+	if c := w.Config(); c.GetRequiredString("b") != "bar" {
+		t.Errorf("Config not reloaded: %v", c)
+	}
+}
+
+func TestWatcherStartStop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-watcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.json")
+
+	writeConfig(t, path, `{"a": 1}`, time.Now())
+	w, err := NewWatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Interval = time.Millisecond
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				w.Start()
+				w.Stop()
+			}
+		}()
+	}
+	wg.Wait()
+	w.Stop()
+}
+
+func TestWatcherConcurrentReloads(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-watcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.json")
+
+	writeConfig(t, path, `{"a": 0}`, time.Now())
+	w, err := NewWatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// slow loads make overlapping reloads finish in any order
+	w.Loader = func(path string) (Config, error) {
+		c, err := Load(path)
+		time.Sleep(time.Duration(rand.Intn(10)) * time.Millisecond)
+		return c, err
+	}
+	w.Subscribe(func(old, new Config, d Diff) {
+		if new.GetRequiredInt64("a") < old.GetRequiredInt64("a") {
+			t.Errorf("Older config applied: %v after %v", new, old)
+		}
+	})
+
+	wg := sync.WaitGroup{}
+	for i := 1; i <= 50; i++ {
+		writeConfig(t, path, fmt.Sprintf(`{"a": %d}`, i), time.Now())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.Reload()
+		}()
+		// let the reload read the file before it is rewritten
+		time.Sleep(time.Millisecond)
+	}
+	wg.Wait()
+}
@@ -1,44 +1,301 @@
 package datastructs
 
-// BitSet structure definition
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"math/bits"
+)
+
+const (
+	wordSize = 64
+)
+
+var (
+	// ErrInvalidBitSet is returned when unmarshalling invalid BitSet data
+	ErrInvalidBitSet = errors.New("Invalid BitSet")
+)
+
+// BitSet structure definition. A BitSet has a fixed size unless created
+// with NewResizableBitSet, in which case it grows as bits are set.
 type BitSet struct {
-	size int
-	set  []uint8
+	size      int
+	words     []uint64
+	resizable bool
+}
+
+func wordsFor(size int) int {
+	return (size + wordSize - 1) / wordSize
 }
 
 // NewBitSet creates a new bitset
 func NewBitSet(size int) (bs *BitSet) {
-	bs = &BitSet{}
-	bs.size = size
-	if size%8 == 0 {
-		bs.set = make([]uint8, size/8)
-	} else {
-		bs.set = make([]uint8, (size/8)+1)
-	}
+	return &BitSet{size: size, words: make([]uint64, wordsFor(size))}
+}
+
+// NewResizableBitSet creates a new bitset growing as bits beyond its size are
+// set or toggled
+func NewResizableBitSet(size int) (bs *BitSet) {
+	bs = NewBitSet(size)
+	bs.resizable = true
 	return
 }
 
-// Set bit at offset o
+// index returns the word index and the mask of bit o, growing the BitSet if
+// needed and allowed. ok is false if o is out of range.
+func (b *BitSet) index(o int, grow bool) (i int, mask uint64, ok bool) {
+	if o < 0 || o >= b.size {
+		if !grow || !b.resizable || o < 0 {
+			return
+		}
+		b.Resize(o + 1)
+	}
+	return o / wordSize, 1 << uint(o%wordSize), true
+}
+
+// Resize changes the size of the BitSet, bits beyond the new size being
+// cleared
+func (b *BitSet) Resize(size int) {
+	// words beyond len(b.words) are always zero
+	switch n := wordsFor(size); {
+	case n > cap(b.words):
+		// amortize the growth of resizable sets
+		words := make([]uint64, n, n+n/2)
+		copy(words, b.words)
+		b.words = words
+	case n > len(b.words):
+		b.words = b.words[:n]
+	default:
+		clear(b.words[n:])
+		b.words = b.words[:n]
+	}
+	b.size = size
+	b.clearTail()
+}
+
+// clearTail clears the bits of the last word beyond the size
+func (b *BitSet) clearTail() {
+	if r := b.size % wordSize; r != 0 {
+		b.words[len(b.words)-1] &= 1<<uint(r) - 1
+	}
+}
+
+// Set bit at offset o, offsets out of the range of a fixed size BitSet
+// being ignored
 func (b *BitSet) Set(o int) {
-	bucketID := o / 8
-	oInBucket := uint8(o % 8)
-	if o >= b.size {
-		return
+	if i, mask, ok := b.index(o, true); ok {
+		b.words[i] |= mask
 	}
-	b.set[bucketID] = (b.set[bucketID] | 0x1<<oInBucket)
 }
 
-// Get the value of bit at offset o
+// Clear bit at offset o, offsets out of range being ignored
+func (b *BitSet) Clear(o int) {
+	if i, mask, ok := b.index(o, false); ok {
+		b.words[i] &^= mask
+	}
+}
+
+// Toggle bit at offset o, offsets out of the range of a fixed size BitSet
+// being ignored
+func (b *BitSet) Toggle(o int) {
+	if i, mask, ok := b.index(o, true); ok {
+		b.words[i] ^= mask
+	}
+}
+
+// Get the value of bit at offset o, bits out of range being false
 func (b *BitSet) Get(o int) bool {
-	bucketID := o / 8
-	oInBucket := uint8(o % 8)
-	if o >= b.size {
+	if o < 0 || o >= b.size {
 		return false
 	}
-	return (b.set[bucketID]&(0x1<<oInBucket))>>oInBucket == 0x1
+	return b.words[o/wordSize]&(1<<uint(o%wordSize)) != 0
 }
 
 // Len returns the length of the BitSet
 func (b *BitSet) Len() int {
 	return b.size
 }
+
+// Count returns the number of bits set
+func (b *BitSet) Count() (n int) {
+	for _, w := range b.words {
+		n += bits.OnesCount64(w)
+	}
+	return
+}
+
+// NextSet returns the offset of the first bit set from offset from, ok being
+// false if there is none
+func (b *BitSet) NextSet(from int) (o int, ok bool) {
+	return b.next(from, 0)
+}
+
+// NextClear returns the offset of the first bit cleared from offset from, ok
+// being false if there is none
+func (b *BitSet) NextClear(from int) (o int, ok bool) {
+	return b.next(from, ^uint64(0))
+}
+
+// next returns the first bit set in the words xor flip from offset from
+func (b *BitSet) next(from int, flip uint64) (int, bool) {
+	if from < 0 {
+		from = 0
+	}
+	if from >= b.size {
+		return 0, false
+	}
+	o := -1
+	i := from / wordSize
+	if w := (b.words[i] ^ flip) >> uint(from%wordSize); w != 0 {
+		o = from + bits.TrailingZeros64(w)
+	}
+	for i++; o < 0 && i < len(b.words); i++ {
+		if w := b.words[i] ^ flip; w != 0 {
+			o = i*wordSize + bits.TrailingZeros64(w)
+		}
+	}
+	// flipped bits beyond the size are set
+	if o < 0 || o >= b.size {
+		return 0, false
+	}
+	return o, true
+}
+
+// Range calls fn with the offsets of the bits set until fn returns false
+func (b *BitSet) Range(fn func(int) bool) {
+	for i, w := range b.words {
+		for w != 0 {
+			if !fn(i*wordSize + bits.TrailingZeros64(w)) {
+				return
+			}
+			// clear lowest bit set
+			w &= w - 1
+		}
+	}
+}
+
+// All returns an iterator over the offsets of the bits set
+func (b *BitSet) All() iter.Seq[int] {
+	return b.Range
+}
+
+// combine applies op to the words of b and other, missing words of other
+// being zero. A resizable BitSet grows to the size of other if needed.
+func (b *BitSet) combine(other *BitSet, op func(x, y uint64) uint64) *BitSet {
+	if b.resizable && other.size > b.size {
+		b.Resize(other.size)
+	}
+	for i := range b.words {
+		var y uint64
+		if i < len(other.words) {
+			y = other.words[i]
+		}
+		b.words[i] = op(b.words[i], y)
+	}
+	b.clearTail()
+	return b
+}
+
+// And sets b to the intersection of b and other and returns b
+func (b *BitSet) And(other *BitSet) *BitSet {
+	return b.combine(other, func(x, y uint64) uint64 { return x & y })
+}
+
+// Or sets b to the union of b and other and returns b
+func (b *BitSet) Or(other *BitSet) *BitSet {
+	return b.combine(other, func(x, y uint64) uint64 { return x | y })
+}
+
+// Xor sets b to the symmetric difference of b and other and returns b
+func (b *BitSet) Xor(other *BitSet) *BitSet {
+	return b.combine(other, func(x, y uint64) uint64 { return x ^ y })
+}
+
+// AndNot clears the bits of b set in other and returns b
+func (b *BitSet) AndNot(other *BitSet) *BitSet {
+	return b.combine(other, func(x, y uint64) uint64 { return x &^ y })
+}
+
+// Equal returns true if both BitSets have the same size and bits
+func (b *BitSet) Equal(other *BitSet) bool {
+	if b.size != other.size {
+		return false
+	}
+	for i := range b.words {
+		if b.words[i] != other.words[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Copy returns a copy of the BitSet
+func (b *BitSet) Copy() *BitSet {
+	new := &BitSet{size: b.size, words: make([]uint64, len(b.words)), resizable: b.resizable}
+	copy(new.words, b.words)
+	return new
+}
+
+// bytes returns the bits of the BitSet, bit o being bit o%8 of byte o/8
+func (b *BitSet) bytes() []byte {
+	out := make([]byte, (b.size+7)/8)
+	for i := range out {
+		out[i] = byte(b.words[i/8] >> uint(i%8*8))
+	}
+	return out
+}
+
+// bitSetFromBytes creates a BitSet of size bits from data laid out as
+// returned by BitSet.bytes
+func bitSetFromBytes(size int, data []byte) *BitSet {
+	b := NewBitSet(size)
+	for i, x := range data {
+		b.words[i/8] |= uint64(x) << uint(i%8*8)
+	}
+	b.clearTail()
+	return b
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler interface, the size
+// being encoded as an uvarint followed by the bits, bit o being bit o%8 of
+// byte o/8
+func (b *BitSet) MarshalBinary() ([]byte, error) {
+	return append(binary.AppendUvarint(nil, uint64(b.size)), b.bytes()...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler interface, the
+// BitSet keeping its mode (c.f. NewResizableBitSet)
+func (b *BitSet) UnmarshalBinary(data []byte) error {
+	size, n := binary.Uvarint(data)
+	if n <= 0 || size > uint64(len(data)-n)*8 || uint64(len(data)-n) != (size+7)/8 {
+		return fmt.Errorf("%w: %d bytes of data", ErrInvalidBitSet, len(data))
+	}
+	*b = BitSet{resizable: b.resizable, size: int(size), words: bitSetFromBytes(int(size), data[n:]).words}
+	return nil
+}
+
+// bitSetJSON is the JSON representation of a BitSet
+type bitSetJSON struct {
+	Size int    `json:"size"`
+	Bits []byte `json:"bits"`
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (b *BitSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bitSetJSON{b.size, b.bytes()})
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (b *BitSet) UnmarshalJSON(data []byte) error {
+	var j bitSetJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if j.Size < 0 || len(j.Bits) != (j.Size+7)/8 {
+		return fmt.Errorf("%w: %d bytes for %d bits", ErrInvalidBitSet, len(j.Bits), j.Size)
+	}
+	*b = BitSet{resizable: b.resizable, size: j.Size, words: bitSetFromBytes(j.Size, j.Bits).words}
+	return nil
+}
@@ -1,6 +1,9 @@
 package datastructs
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math/rand"
 	"testing"
 )
@@ -14,6 +17,16 @@ func TestBitSetBasic(t *testing.T) {
 		t.Logf("Failed to retrieve bit at offset: %d", offset)
 		t.FailNow()
 	}
+
+	// offsets out of range are ignored
+	for _, o := range []int{-100, -1, 255, 1000} {
+		bs.Set(o)
+		bs.Toggle(o)
+		bs.Clear(o)
+	}
+	if bs.Len() != 255 || bs.Count() != 1 {
+		t.Error("Out of range offsets must be ignored")
+	}
 }
 
 func TestBitSetRookie(t *testing.T) {
@@ -54,3 +67,113 @@ func TestBitSetHardcore(t *testing.T) {
 		}
 	}
 }
+
+func TestBitSetOperations(t *testing.T) {
+	bs := NewBitSet(130)
+	for _, o := range []int{0, 63, 64, 129} {
+		bs.Set(o)
+	}
+	bs.Toggle(1)
+	bs.Toggle(0)
+	bs.Clear(64)
+	if bs.Count() != 3 || bs.Get(0) || !bs.Get(1) || bs.Get(64) || bs.Get(1000) {
+		t.Errorf("Bad bits: %v", bs.bytes())
+	}
+
+	var set []int
+	for o, ok := bs.NextSet(0); ok; o, ok = bs.NextSet(o + 1) {
+		set = append(set, o)
+	}
+	var all []int
+	for o := range bs.All() {
+		all = append(all, o)
+	}
+	if fmt.Sprint(set) != "[1 63 129]" || fmt.Sprint(all) != fmt.Sprint(set) {
+		t.Errorf("Bad iteration: %v %v", set, all)
+	}
+	if o, ok := bs.NextClear(63); !ok || o != 64 {
+		t.Errorf("Bad next clear bit: %d", o)
+	}
+	if _, ok := bs.NextClear(129); ok {
+		t.Error("There must be no clear bit after the last one")
+	}
+
+	other := NewBitSet(64)
+	other.Set(1)
+	other.Set(2)
+	if c := bs.Copy().And(other); c.Count() != 1 || !c.Get(1) {
+		t.Error("Bad and")
+	}
+	if c := bs.Copy().Or(other); c.Count() != 4 || !c.Get(2) || c.Len() != 130 {
+		t.Error("Bad or")
+	}
+	if c := bs.Copy().Xor(other); c.Count() != 3 || c.Get(1) {
+		t.Error("Bad xor")
+	}
+	if c := bs.Copy().AndNot(other); c.Count() != 2 || c.Get(1) {
+		t.Error("Bad and not")
+	}
+	if !bs.Equal(bs.Copy()) || bs.Equal(other) {
+		t.Error("Bad equality")
+	}
+
+	count := bs.Count()
+	bs.Set(130)
+	bs.Clear(-1)
+	if bs.Len() != 130 || bs.Count() != count {
+		t.Error("out of range offsets should be ignored")
+	}
+}
+
+func TestBitSetResizable(t *testing.T) {
+	bs := NewResizableBitSet(0)
+	bs.Set(1000)
+	bs.Toggle(10)
+	if bs.Len() != 1001 || bs.Count() != 2 {
+		t.Errorf("Bad resizable set: %d", bs.Len())
+	}
+	// shrinking clears the bits beyond the size
+	bs.Resize(11)
+	bs.Resize(2000)
+	if bs.Count() != 1 || bs.Get(1000) {
+		t.Error("Bits beyond the size must be cleared")
+	}
+	other := NewBitSet(3000)
+	other.Set(2999)
+	if bs.Or(other); bs.Len() != 3000 || !bs.Get(2999) {
+		t.Error("Resizable set must grow")
+	}
+}
+
+func TestBitSetSerialization(t *testing.T) {
+	bs := NewBitSet(1013)
+	for i := 0; i < bs.Len(); i += 3 {
+		bs.Set(i)
+	}
+
+	data, err := bs.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nbs BitSet
+	if err := nbs.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !nbs.Equal(bs) {
+		t.Error("Bad binary unmarshalling")
+	}
+	if err := nbs.UnmarshalBinary(data[:len(data)-1]); !errors.Is(err, ErrInvalidBitSet) {
+		t.Errorf("Expecting invalid BitSet error: %v", err)
+	}
+
+	if data, err = json.Marshal(bs); err != nil {
+		t.Fatal(err)
+	}
+	nbs = BitSet{}
+	if err := json.Unmarshal(data, &nbs); err != nil {
+		t.Fatal(err)
+	}
+	if !nbs.Equal(bs) {
+		t.Error("Bad JSON unmarshalling")
+	}
+}
@@ -0,0 +1,340 @@
+package datastructs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/0xrawsec/golang-utils/encoding"
+)
+
+const (
+	bloomKind         = 0
+	countingBloomKind = 1
+	// maxCount is the value at which counters of counting filters saturate,
+	// saturated counters being never decremented
+	maxCount = math.MaxUint8
+	// maxHashCount bounds the number of hash functions, the optimal number
+	// being log2(1/p) for a false positive rate p
+	maxHashCount = 64
+)
+
+var (
+	// ErrIncompatibleFilters is returned when combining filters of different
+	// sizes or number of hash functions
+	ErrIncompatibleFilters = errors.New("Incompatible filters")
+	// ErrInvalidFilter is returned when unmarshalling invalid filter data
+	ErrInvalidFilter = errors.New("Invalid filter")
+)
+
+// bloomData is the binary format of filters
+type bloomData struct {
+	Magic [4]byte `bin:"magic=BLMF"`
+	Kind  uint8
+	K     uint32
+	M     uint64
+	Data  []byte `bin:"lenprefix=uleb128"`
+	CRC   uint32 `bin:"crc32=Kind:Data"`
+}
+
+func (d *bloomData) marshal() ([]byte, error) {
+	return encoding.Marshal(d, binary.LittleEndian)
+}
+
+func (d *bloomData) unmarshal(data []byte, kind uint8) error {
+	if err := encoding.Unmarshal(bytes.NewReader(data), d, binary.LittleEndian); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidFilter, err)
+	}
+	if d.Kind != kind || d.K == 0 || d.K > maxHashCount || d.M == 0 || d.M > math.MaxInt {
+		return fmt.Errorf("%w: kind=%d k=%d m=%d", ErrInvalidFilter, d.Kind, d.K, d.M)
+	}
+	return nil
+}
+
+// BloomParams returns the number of bits m and the number of hash functions
+// k of a Bloom filter holding n elements with a false positive rate p, k
+// being at most 64
+func BloomParams(n int, p float64) (m, k int) {
+	if p <= 0 || p >= 1 {
+		panic("false positive rate must be in ]0, 1[")
+	}
+	if n < 1 {
+		n = 1
+	}
+	m = int(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k = int(math.Round(float64(m) / float64(n) * math.Ln2))
+	k = min(max(k, 1), maxHashCount)
+	return
+}
+
+// bloomHashes returns the two hashes of data used for double hashing
+func bloomHashes(data []byte) (h1, h2 uint64) {
+	h := fnv.New128a()
+	h.Write(data)
+	sum := h.Sum(nil)
+	// an odd h2 makes the positions differ on filters of even size
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:]) | 1
+}
+
+// bloomPositions calls fn with the k positions of data in a filter of m
+// bits until fn returns false
+func bloomPositions(data []byte, k, m uint64, fn func(uint64) bool) bool {
+	h1, h2 := bloomHashes(data)
+	for i := uint64(0); i < k; i++ {
+		if !fn((h1 + i*h2) % m) {
+			return false
+		}
+	}
+	return true
+}
+
+// BloomFilter is a space efficient probabilistic set: Contains never returns
+// false for an element added but may return true for an element not added
+type BloomFilter struct {
+	bits *BitSet
+	k    uint64
+}
+
+// NewBloomFilter creates a BloomFilter sized to hold n elements with a false
+// positive rate p
+func NewBloomFilter(n int, p float64) *BloomFilter {
+	m, k := BloomParams(n, p)
+	return &BloomFilter{NewBitSet(m), uint64(k)}
+}
+
+func (b *BloomFilter) m() uint64 {
+	return uint64(b.bits.Len())
+}
+
+// Add adds data to the filter
+func (b *BloomFilter) Add(data []byte) {
+	bloomPositions(data, b.k, b.m(), func(pos uint64) bool {
+		b.bits.Set(int(pos))
+		return true
+	})
+}
+
+// AddString adds s to the filter
+func (b *BloomFilter) AddString(s string) {
+	b.Add([]byte(s))
+}
+
+// Contains returns true if data may have been added to the filter
+func (b *BloomFilter) Contains(data []byte) bool {
+	return bloomPositions(data, b.k, b.m(), func(pos uint64) bool {
+		return b.bits.Get(int(pos))
+	})
+}
+
+// ContainsString returns true if s may have been added to the filter
+func (b *BloomFilter) ContainsString(s string) bool {
+	return b.Contains([]byte(s))
+}
+
+// Len returns the number of bits of the filter
+func (b *BloomFilter) Len() int {
+	return b.bits.Len()
+}
+
+// HashCount returns the number of hash functions of the filter
+func (b *BloomFilter) HashCount() int {
+	return int(b.k)
+}
+
+func (b *BloomFilter) compatible(other *BloomFilter) error {
+	if b.k != other.k || b.m() != other.m() {
+		return fmt.Errorf("%w: k=%d m=%d and k=%d m=%d", ErrIncompatibleFilters, b.k, b.m(), other.k, other.m())
+	}
+	return nil
+}
+
+// combine returns a new filter whose bytes are op of the bytes of b and other
+func (b *BloomFilter) combine(other *BloomFilter, op func(x, y byte) byte) (*BloomFilter, error) {
+	if err := b.compatible(other); err != nil {
+		return nil, err
+	}
+	x, y := b.bits.bytes(), other.bits.bytes()
+	for i := range x {
+		x[i] = op(x[i], y[i])
+	}
+	return &BloomFilter{bitSetFromBytes(b.bits.Len(), x), b.k}, nil
+}
+
+// Union returns a new filter containing the elements of both filters, which
+// must have the same size and number of hash functions
+func (b *BloomFilter) Union(other *BloomFilter) (*BloomFilter, error) {
+	return b.combine(other, func(x, y byte) byte { return x | y })
+}
+
+// Intersect returns a new filter containing the elements of both filters,
+// which must have the same size and number of hash functions. The result
+// has a higher false positive rate than a filter built from the elements of
+// the intersection.
+func (b *BloomFilter) Intersect(other *BloomFilter) (*BloomFilter, error) {
+	return b.combine(other, func(x, y byte) byte { return x & y })
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler interface
+func (b *BloomFilter) MarshalBinary() ([]byte, error) {
+	d := bloomData{Kind: bloomKind, K: uint32(b.k), M: b.m(), Data: b.bits.bytes()}
+	return d.marshal()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler interface
+func (b *BloomFilter) UnmarshalBinary(data []byte) error {
+	var d bloomData
+	if err := d.unmarshal(data, bloomKind); err != nil {
+		return err
+	}
+	// M is checked against the data first so that (M+7)/8 cannot overflow
+	if d.M > uint64(len(d.Data))*8 || uint64(len(d.Data)) != (d.M+7)/8 {
+		return fmt.Errorf("%w: %d bytes for %d bits", ErrInvalidFilter, len(d.Data), d.M)
+	}
+	b.bits, b.k = bitSetFromBytes(int(d.M), d.Data), uint64(d.K)
+	return nil
+}
+
+// CountingBloomFilter is a BloomFilter supporting deletion, at the cost of
+// a 8 bits counter per position. Counters saturate at 255 after which they
+// cannot be decremented anymore.
+type CountingBloomFilter struct {
+	counts []uint8
+	k      uint64
+}
+
+// NewCountingBloomFilter creates a CountingBloomFilter sized to hold n
+// elements with a false positive rate p
+func NewCountingBloomFilter(n int, p float64) *CountingBloomFilter {
+	m, k := BloomParams(n, p)
+	return &CountingBloomFilter{make([]uint8, m), uint64(k)}
+}
+
+func (c *CountingBloomFilter) m() uint64 {
+	return uint64(len(c.counts))
+}
+
+// Add adds data to the filter
+func (c *CountingBloomFilter) Add(data []byte) {
+	bloomPositions(data, c.k, c.m(), func(pos uint64) bool {
+		if c.counts[pos] < maxCount {
+			c.counts[pos]++
+		}
+		return true
+	})
+}
+
+// AddString adds s to the filter
+func (c *CountingBloomFilter) AddString(s string) {
+	c.Add([]byte(s))
+}
+
+// Contains returns true if data may have been added to the filter
+func (c *CountingBloomFilter) Contains(data []byte) bool {
+	return bloomPositions(data, c.k, c.m(), func(pos uint64) bool {
+		return c.counts[pos] > 0
+	})
+}
+
+// ContainsString returns true if s may have been added to the filter
+func (c *CountingBloomFilter) ContainsString(s string) bool {
+	return c.Contains([]byte(s))
+}
+
+// Del deletes data from the filter, data being expected to have been added.
+// It returns false if the filter does not contain data.
+func (c *CountingBloomFilter) Del(data []byte) bool {
+	if !c.Contains(data) {
+		return false
+	}
+	bloomPositions(data, c.k, c.m(), func(pos uint64) bool {
+		if c.counts[pos] < maxCount {
+			c.counts[pos]--
+		}
+		return true
+	})
+	return true
+}
+
+// DelString deletes s from the filter (c.f. Del)
+func (c *CountingBloomFilter) DelString(s string) bool {
+	return c.Del([]byte(s))
+}
+
+// Len returns the number of counters of the filter
+func (c *CountingBloomFilter) Len() int {
+	return len(c.counts)
+}
+
+// HashCount returns the number of hash functions of the filter
+func (c *CountingBloomFilter) HashCount() int {
+	return int(c.k)
+}
+
+// combine returns a new filter whose counters are op of the counters of c
+// and other
+func (c *CountingBloomFilter) combine(other *CountingBloomFilter, op func(x, y uint8) uint8) (*CountingBloomFilter, error) {
+	if c.k != other.k || c.m() != other.m() {
+		return nil, fmt.Errorf("%w: k=%d m=%d and k=%d m=%d", ErrIncompatibleFilters, c.k, c.m(), other.k, other.m())
+	}
+	new := &CountingBloomFilter{make([]uint8, len(c.counts)), c.k}
+	for i := range c.counts {
+		new.counts[i] = op(c.counts[i], other.counts[i])
+	}
+	return new, nil
+}
+
+// Union returns a new filter containing the elements of both filters, which
+// must have the same size and number of hash functions
+func (c *CountingBloomFilter) Union(other *CountingBloomFilter) (*CountingBloomFilter, error) {
+	return c.combine(other, func(x, y uint8) uint8 {
+		if x > maxCount-y {
+			return maxCount
+		}
+		return x + y
+	})
+}
+
+// Intersect returns a new filter containing the elements of both filters,
+// which must have the same size and number of hash functions
+func (c *CountingBloomFilter) Intersect(other *CountingBloomFilter) (*CountingBloomFilter, error) {
+	return c.combine(other, func(x, y uint8) uint8 {
+		if x < y {
+			return x
+		}
+		return y
+	})
+}
+
+// BloomFilter returns a BloomFilter containing the elements of the filter,
+// which is smaller to ship when deletion is not needed
+func (c *CountingBloomFilter) BloomFilter() *BloomFilter {
+	b := &BloomFilter{NewBitSet(len(c.counts)), c.k}
+	for i, count := range c.counts {
+		if count > 0 {
+			b.bits.Set(i)
+		}
+	}
+	return b
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler interface
+func (c *CountingBloomFilter) MarshalBinary() ([]byte, error) {
+	d := bloomData{Kind: countingBloomKind, K: uint32(c.k), M: c.m(), Data: c.counts}
+	return d.marshal()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler interface
+func (c *CountingBloomFilter) UnmarshalBinary(data []byte) error {
+	var d bloomData
+	if err := d.unmarshal(data, countingBloomKind); err != nil {
+		return err
+	}
+	if uint64(len(d.Data)) != d.M {
+		return fmt.Errorf("%w: %d counters for %d positions", ErrInvalidFilter, len(d.Data), d.M)
+	}
+	c.counts, c.k = d.Data, uint64(d.K)
+	return nil
+}
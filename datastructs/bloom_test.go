@@ -0,0 +1,181 @@
+package datastructs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestBloomParams(t *testing.T) {
+	m, k := BloomParams(1000000, 0.01)
+	// ~9.6 bits per element and 7 hash functions
+	if m != 9585059 || k != 7 {
+		t.Errorf("Bad parameters: m=%d k=%d", m, k)
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	n, p := 100000, 0.01
+	b := NewBloomFilter(n, p)
+	for i := 0; i < n; i++ {
+		b.AddString(fmt.Sprintf("ioc-%d", i))
+	}
+	for i := 0; i < n; i++ {
+		if !b.ContainsString(fmt.Sprintf("ioc-%d", i)) {
+			t.Fatalf("Missing element %d", i)
+		}
+	}
+	fp := 0
+	for i := 0; i < n; i++ {
+		if b.ContainsString(fmt.Sprintf("other-%d", i)) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / float64(n); rate > 2*p {
+		t.Errorf("False positive rate too high: %f", rate)
+	}
+
+	data, err := b.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nb BloomFilter
+	if err := nb.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if nb.Len() != b.Len() || nb.HashCount() != b.HashCount() || !nb.ContainsString("ioc-42") {
+		t.Error("Bad unmarshalled filter")
+	}
+
+	data[len(data)/2] ^= 0xff
+	if err := nb.UnmarshalBinary(data); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("Expecting invalid filter error: %v", err)
+	}
+	var cb CountingBloomFilter
+	if err := cb.UnmarshalBinary(data); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("Expecting invalid filter error: %v", err)
+	}
+
+	// well formed data with too many hash functions
+	d := bloomData{Kind: bloomKind, K: maxHashCount + 1, M: b.m(), Data: b.bits.bytes()}
+	if data, err = d.marshal(); err != nil {
+		t.Fatal(err)
+	}
+	if err := nb.UnmarshalBinary(data); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("Expecting invalid filter error: %v", err)
+	}
+
+	// sizes overflowing the size of the data
+	for _, m := range []uint64{math.MaxUint64, math.MaxUint64 - 6, math.MaxInt64 + 1} {
+		d = bloomData{Kind: bloomKind, K: 3, M: m}
+		if data, err = d.marshal(); err != nil {
+			t.Fatal(err)
+		}
+		if err := nb.UnmarshalBinary(data); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("Expecting invalid filter error: %v", err)
+		}
+		d.Kind = countingBloomKind
+		if data, err = d.marshal(); err != nil {
+			t.Fatal(err)
+		}
+		if err := cb.UnmarshalBinary(data); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("Expecting invalid filter error: %v", err)
+		}
+	}
+
+	if _, k := BloomParams(10, 1e-30); k != maxHashCount {
+		t.Errorf("Bad number of hash functions: %d", k)
+	}
+}
+
+func TestBloomFilterSetOperations(t *testing.T) {
+	b1, b2 := NewBloomFilter(1000, 0.001), NewBloomFilter(1000, 0.001)
+	b1.AddString("foo")
+	b1.AddString("both")
+	b2.AddString("bar")
+	b2.AddString("both")
+
+	u, err := b1.Union(b2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !u.ContainsString("foo") || !u.ContainsString("bar") || !u.ContainsString("both") {
+		t.Error("Bad union")
+	}
+	i, err := b1.Intersect(b2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.ContainsString("foo") || i.ContainsString("bar") || !i.ContainsString("both") {
+		t.Error("Bad intersection")
+	}
+	// operands are not modified
+	if b1.ContainsString("bar") {
+		t.Error("Union must not modify filters")
+	}
+
+	if _, err := b1.Union(NewBloomFilter(10, 0.001)); !errors.Is(err, ErrIncompatibleFilters) {
+		t.Errorf("Expecting incompatible filters error: %v", err)
+	}
+}
+
+func TestCountingBloomFilter(t *testing.T) {
+	c := NewCountingBloomFilter(1000, 0.001)
+	for i := 0; i < 100; i++ {
+		c.AddString(fmt.Sprint(i))
+	}
+	for i := 0; i < 50; i++ {
+		if !c.DelString(fmt.Sprint(i)) {
+			t.Errorf("Failed to delete %d", i)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if c.ContainsString(fmt.Sprint(i)) != (i >= 50) {
+			t.Errorf("Bad membership of %d", i)
+		}
+	}
+	if c.DelString("missing") {
+		t.Error("Deleting a missing element must fail")
+	}
+
+	other := NewCountingBloomFilter(1000, 0.001)
+	other.AddString("99")
+	other.AddString("foo")
+	u, err := c.Union(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 99 was added twice
+	u.DelString("99")
+	if !u.ContainsString("99") || !u.ContainsString("foo") {
+		t.Error("Bad union")
+	}
+	i, err := c.Intersect(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !i.ContainsString("99") || i.ContainsString("foo") || i.ContainsString("98") {
+		t.Error("Bad intersection")
+	}
+
+	data, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nc CountingBloomFilter
+	if err := nc.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !nc.ContainsString("50") || !nc.DelString("50") || nc.ContainsString("50") {
+		t.Error("Bad unmarshalled filter")
+	}
+	// counting filters cannot be unmarshalled as filters
+	var b BloomFilter
+	if err := b.UnmarshalBinary(data); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("Expecting invalid filter error: %v", err)
+	}
+	if b := c.BloomFilter(); !b.ContainsString("99") || b.ContainsString("0") {
+		t.Error("Bad filter conversion")
+	}
+}
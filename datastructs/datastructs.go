@@ -1,5 +1,6 @@
 package datastructs
 
-type Hashable interface {
-	Hash() string
-}
+import "github.com/0xrawsec/golang-utils/datastructs/generic"
+
+// Hashable is implemented by the keys of a HashMap
+type Hashable = generic.Hashable
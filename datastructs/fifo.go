@@ -1,82 +1,28 @@
 package datastructs
 
-import (
-	"fmt"
-	"strings"
-	"sync"
+import "github.com/0xrawsec/golang-utils/datastructs/generic"
+
+// Element is an element of a Fifo (c.f. generic.Element)
+type Element = generic.Element[interface{}]
+
+// Fifo is a thread safe first in first out queue (c.f. generic.Fifo)
+type Fifo = generic.Fifo[interface{}]
+
+var (
+	// ErrQueueClosed is returned when pushing to a closed Queue or popping
+	// from a closed and drained Queue
+	ErrQueueClosed = generic.ErrQueueClosed
+	// ErrQueueFull is returned by Queue.TryPush when the Queue is full
+	ErrQueueFull = generic.ErrQueueFull
+	// ErrQueueEmpty is returned by Queue.TryPop when the Queue is empty
+	ErrQueueEmpty = generic.ErrQueueEmpty
 )
 
-type Element struct {
-	Value interface{}
-	Prev  *Element
-	Next  *Element
-}
-
-func (e *Element) String() string {
-	return fmt.Sprintf("(%T(%v), %p, %p)", e.Value, e.Value, e.Prev, e.Next)
-}
-
-type Fifo struct {
-	sync.RWMutex
-	e    *Element
-	last *Element
-	size int
-}
-
-func (f *Fifo) Push(i interface{}) {
-	f.Lock()
-	defer f.Unlock()
-	e := Element{Value: i}
-	if f.e == nil {
-		f.e = &e
-		f.last = &e
-	} else {
-		e.Next = f.e
-		f.e.Prev = &e
-		f.e = &e
-	}
-	f.size++
-}
-
-func (f *Fifo) String() string {
-	f.RLock()
-	defer f.RUnlock()
-	out := make([]string, 0)
-	for e := f.e; e != nil; e = e.Next {
-		out = append(out, e.String())
-	}
-	return strings.Join(out, "->")
-}
-
-func (f *Fifo) Empty() bool {
-	f.RLock()
-	defer f.RUnlock()
-	return f.size == 0
-}
-
-func (f *Fifo) Pop() *Element {
-	f.Lock()
-	defer f.Unlock()
-	if f.last == nil {
-		return nil
-	}
-
-	popped := f.last
-	f.last = f.last.Prev
-	if f.last != nil {
-		f.last.Next = nil
-	}
-	// we have to nil out f.e if we pop
-	// the last element of the Fifo
-	if f.size == 1 {
-		f.e = nil
-	}
-	f.size--
-	return popped
-}
+// Queue is a thread safe bounded first in first out queue whose pushes and
+// pops wait, unlike a Fifo (c.f. generic.Queue)
+type Queue = generic.Queue[interface{}]
 
-func (f *Fifo) Len() int {
-	f.RLock()
-	defer f.RUnlock()
-	return f.size
+// NewQueue returns an empty Queue holding at most capacity elements
+func NewQueue(capacity int) *Queue {
+	return generic.NewQueue[interface{}](capacity)
 }
@@ -1,6 +1,9 @@
 package datastructs
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func TestFifoBasic(t *testing.T) {
 	f := &Fifo{}
@@ -29,3 +32,19 @@ func TestFifoBasic(t *testing.T) {
 		t.Error("Fifo should be empty")
 	}
 }
+
+func TestQueue(t *testing.T) {
+	q := NewQueue(2)
+	q.TryPush(1)
+	q.TryPush(2)
+	if err := q.TryPush(3); err != ErrQueueFull {
+		t.Errorf("Expecting full queue error: %v", err)
+	}
+	q.Close()
+	for e := range q.Drain(context.Background()) {
+		t.Logf("popped: %v", e)
+	}
+	if _, err := q.TryPop(); err != ErrQueueClosed {
+		t.Errorf("Expecting closed queue error: %v", err)
+	}
+}
@@ -0,0 +1,384 @@
+package generic
+
+import (
+	"context"
+	"iter"
+	"sync"
+	"time"
+)
+
+// CacheStats holds the counters of a cache
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+	// Evictions is the number of entries removed to respect the capacity
+	Evictions uint64
+	// Expirations is the number of expired entries removed
+	Expirations uint64
+}
+
+// EvictFunc is called with the entries evicted from a cache, either to
+// respect its capacity or because they expired
+type EvictFunc[K, V any] func(key K, value V)
+
+type cacheEntry[K, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+	prev    *cacheEntry[K, V]
+	next    *cacheEntry[K, V]
+}
+
+// Cache is a cache with an optional capacity, the least recently used
+// entries being evicted when full, and optional entry expiry. Keys are
+// indexed by a hash H of the key, which is the key itself for comparable
+// keys (c.f. NewLRU) and Hash() for Hashable ones (c.f. NewHashLRU). It is
+// not thread safe (c.f. SyncedCache).
+type Cache[H comparable, K, V any] struct {
+	hash     func(K) H
+	capacity int
+	ttl      time.Duration
+	onEvict  EvictFunc[K, V]
+	items    map[H]*cacheEntry[K, V]
+	// most recently used entry first
+	head, tail *cacheEntry[K, V]
+	stats      CacheStats
+	// number of entries with an expiry
+	expiring int
+	now      func() time.Time
+}
+
+func newCache[H comparable, K, V any](hash func(K) H, capacity int, ttl time.Duration, onEvict EvictFunc[K, V]) *Cache[H, K, V] {
+	return &Cache[H, K, V]{
+		hash:     hash,
+		capacity: capacity,
+		ttl:      ttl,
+		onEvict:  onEvict,
+		items:    make(map[H]*cacheEntry[K, V]),
+		now:      time.Now,
+	}
+}
+
+func identity[K any](k K) K {
+	return k
+}
+
+func hashKey[K Hashable](k K) string {
+	return k.Hash()
+}
+
+// NewLRU creates a Cache holding at most capacity entries, onEvict being
+// called with the evicted entries if not nil
+func NewLRU[K comparable, V any](capacity int, onEvict EvictFunc[K, V]) *Cache[K, K, V] {
+	return newCache(identity[K], capacity, 0, onEvict)
+}
+
+// NewTTLCache creates an unbounded Cache whose entries expire after ttl by
+// default, onEvict being called with the expired entries if not nil
+func NewTTLCache[K comparable, V any](ttl time.Duration, onEvict EvictFunc[K, V]) *Cache[K, K, V] {
+	return newCache(identity[K], 0, ttl, onEvict)
+}
+
+// NewHashLRU creates a Cache of Hashable keys holding at most capacity
+// entries (c.f. NewLRU)
+func NewHashLRU[K Hashable, V any](capacity int, onEvict EvictFunc[K, V]) *Cache[string, K, V] {
+	return newCache(hashKey[K], capacity, 0, onEvict)
+}
+
+// NewHashTTLCache creates an unbounded Cache of Hashable keys whose entries
+// expire after ttl by default (c.f. NewTTLCache)
+func NewHashTTLCache[K Hashable, V any](ttl time.Duration, onEvict EvictFunc[K, V]) *Cache[string, K, V] {
+	return newCache(hashKey[K], 0, ttl, onEvict)
+}
+
+func (c *Cache[H, K, V]) unlink(e *cacheEntry[K, V]) {
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else {
+		c.head = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		c.tail = e.prev
+	}
+	e.prev, e.next = nil, nil
+}
+
+func (c *Cache[H, K, V]) pushFront(e *cacheEntry[K, V]) {
+	e.next = c.head
+	if c.head != nil {
+		c.head.prev = e
+	}
+	c.head = e
+	if c.tail == nil {
+		c.tail = e
+	}
+}
+
+// remove removes e from the cache, calling the eviction callback if evicted
+func (c *Cache[H, K, V]) remove(e *cacheEntry[K, V], evicted bool) {
+	c.unlink(e)
+	delete(c.items, c.hash(e.key))
+	if !e.expires.IsZero() {
+		c.expiring--
+	}
+	if evicted && c.onEvict != nil {
+		c.onEvict(e.key, e.value)
+	}
+}
+
+func (c *Cache[H, K, V]) expired(e *cacheEntry[K, V], now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// Add adds or updates key with value, using the default time to live of
+// the cache. When the cache is full the expired entries are removed and, if
+// none expired, the least recently used entry is evicted.
+func (c *Cache[H, K, V]) Add(key K, value V) {
+	c.AddWithTTL(key, value, c.ttl)
+}
+
+// AddWithTTL adds or updates key with value, expiring after ttl (0 meaning
+// the entry never expires)
+func (c *Cache[H, K, V]) AddWithTTL(key K, value V, ttl time.Duration) {
+	var expires time.Time
+	if ttl > 0 {
+		expires = c.now().Add(ttl)
+		c.expiring++
+	}
+	h := c.hash(key)
+	if e, ok := c.items[h]; ok {
+		if !e.expires.IsZero() {
+			c.expiring--
+		}
+		e.key, e.value, e.expires = key, value, expires
+		c.unlink(e)
+		c.pushFront(e)
+		return
+	}
+	e := &cacheEntry[K, V]{key: key, value: value, expires: expires}
+	c.items[h] = e
+	c.pushFront(e)
+	if c.capacity > 0 && len(c.items) > c.capacity {
+		// expired entries are dropped before evicting live ones
+		if c.expiring > 0 {
+			c.Cleanup()
+		}
+		if len(c.items) > c.capacity {
+			c.stats.Evictions++
+			c.remove(c.tail, true)
+		}
+	}
+}
+
+// Get returns the value of key and marks it as the most recently used
+func (c *Cache[H, K, V]) Get(key K) (value V, ok bool) {
+	e, ok := c.items[c.hash(key)]
+	if ok && c.expired(e, c.now()) {
+		c.stats.Expirations++
+		c.remove(e, true)
+		ok = false
+	}
+	if !ok {
+		c.stats.Misses++
+		return
+	}
+	c.stats.Hits++
+	c.unlink(e)
+	c.pushFront(e)
+	return e.value, true
+}
+
+// Peek returns the value of key without updating the usage of the entry nor
+// the counters
+func (c *Cache[H, K, V]) Peek(key K) (value V, ok bool) {
+	if e, ok := c.items[c.hash(key)]; ok && !c.expired(e, c.now()) {
+		return e.value, true
+	}
+	return
+}
+
+// Contains returns true if the cache contains key, without updating the
+// usage of the entry nor the counters
+func (c *Cache[H, K, V]) Contains(key K) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// Del deletes key from the cache, returning true if it was there. The
+// eviction callback is not called.
+func (c *Cache[H, K, V]) Del(key K) bool {
+	e, ok := c.items[c.hash(key)]
+	if ok {
+		c.remove(e, false)
+	}
+	return ok
+}
+
+// Cleanup removes the expired entries and returns the number of entries
+// removed. Expired entries are otherwise removed lazily when accessed.
+func (c *Cache[H, K, V]) Cleanup() (n int) {
+	now := c.now()
+	for e := c.head; e != nil; {
+		next := e.next
+		if c.expired(e, now) {
+			c.stats.Expirations++
+			c.remove(e, true)
+			n++
+		}
+		e = next
+	}
+	return
+}
+
+// Purge removes all the entries without calling the eviction callback
+func (c *Cache[H, K, V]) Purge() {
+	c.items = make(map[H]*cacheEntry[K, V])
+	c.head, c.tail = nil, nil
+	c.expiring = 0
+}
+
+// Range calls fn for the entries of the cache, from the most to the least
+// recently used, until fn returns false. Expired entries not yet cleaned up
+// are skipped.
+func (c *Cache[H, K, V]) Range(fn func(K, V) bool) {
+	now := c.now()
+	for e := c.head; e != nil; e = e.next {
+		if !c.expired(e, now) && !fn(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over the entries of the cache (c.f. Range)
+func (c *Cache[H, K, V]) All() iter.Seq2[K, V] {
+	return c.Range
+}
+
+// Len returns the number of entries in the cache, including the expired
+// entries not yet cleaned up
+func (c *Cache[H, K, V]) Len() int {
+	return len(c.items)
+}
+
+// Stats returns the counters of the cache
+func (c *Cache[H, K, V]) Stats() CacheStats {
+	return c.stats
+}
+
+// SyncedCache is a thread safe Cache, whose methods are the ones of Cache.
+// The eviction callback is called with the cache locked.
+type SyncedCache[H comparable, K, V any] struct {
+	sync.Mutex
+	c *Cache[H, K, V]
+}
+
+// NewSyncedCache makes c thread safe, c must not be used directly anymore
+func NewSyncedCache[H comparable, K, V any](c *Cache[H, K, V]) *SyncedCache[H, K, V] {
+	return &SyncedCache[H, K, V]{c: c}
+}
+
+// Add adds or updates key with value (c.f. Cache.Add)
+func (s *SyncedCache[H, K, V]) Add(key K, value V) {
+	s.Lock()
+	defer s.Unlock()
+	s.c.Add(key, value)
+}
+
+// AddWithTTL adds or updates key with value expiring after ttl (c.f.
+// Cache.AddWithTTL)
+func (s *SyncedCache[H, K, V]) AddWithTTL(key K, value V, ttl time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.c.AddWithTTL(key, value, ttl)
+}
+
+// Get returns the value of key (c.f. Cache.Get)
+func (s *SyncedCache[H, K, V]) Get(key K) (V, bool) {
+	s.Lock()
+	defer s.Unlock()
+	return s.c.Get(key)
+}
+
+// Peek returns the value of key without updating its usage (c.f.
+// Cache.Peek)
+func (s *SyncedCache[H, K, V]) Peek(key K) (V, bool) {
+	s.Lock()
+	defer s.Unlock()
+	return s.c.Peek(key)
+}
+
+// Contains returns true if the cache contains key (c.f. Cache.Contains)
+func (s *SyncedCache[H, K, V]) Contains(key K) bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.c.Contains(key)
+}
+
+// Del deletes key from the cache (c.f. Cache.Del)
+func (s *SyncedCache[H, K, V]) Del(key K) bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.c.Del(key)
+}
+
+// Cleanup removes the expired entries (c.f. Cache.Cleanup)
+func (s *SyncedCache[H, K, V]) Cleanup() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.c.Cleanup()
+}
+
+// CleanupEvery starts a goroutine removing the expired entries every
+// interval until ctx is done
+func (s *SyncedCache[H, K, V]) CleanupEvery(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Cleanup()
+			}
+		}
+	}()
+}
+
+// Purge removes all the entries (c.f. Cache.Purge)
+func (s *SyncedCache[H, K, V]) Purge() {
+	s.Lock()
+	defer s.Unlock()
+	s.c.Purge()
+}
+
+// Range calls fn for the entries of the cache until fn returns false (c.f.
+// Cache.Range). The cache is locked during the iteration so fn must not use
+// it.
+func (s *SyncedCache[H, K, V]) Range(fn func(K, V) bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.c.Range(fn)
+}
+
+// All returns an iterator over the entries of the cache, which is locked
+// during the iteration (c.f. Range)
+func (s *SyncedCache[H, K, V]) All() iter.Seq2[K, V] {
+	return s.Range
+}
+
+// Len returns the number of entries in the cache (c.f. Cache.Len)
+func (s *SyncedCache[H, K, V]) Len() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.c.Len()
+}
+
+// Stats returns the counters of the cache
+func (s *SyncedCache[H, K, V]) Stats() CacheStats {
+	s.Lock()
+	defer s.Unlock()
+	return s.c.Stats()
+}
@@ -0,0 +1,132 @@
+package generic
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	evicted := make(map[int]string)
+	c := NewLRU(3, func(k int, v string) { evicted[k] = v })
+	for i := 0; i < 3; i++ {
+		c.Add(i, fmt.Sprint(i))
+	}
+	// 0 becomes the most recently used so 1 is evicted first
+	if v, ok := c.Get(0); !ok || v != "0" {
+		t.Errorf("Bad value: %q", v)
+	}
+	c.Add(3, "3")
+	c.Add(4, "4")
+	if len(evicted) != 2 || evicted[1] != "1" || evicted[2] != "2" {
+		t.Errorf("Bad evictions: %v", evicted)
+	}
+	if _, ok := c.Get(1); ok || c.Len() != 3 {
+		t.Error("Key must be evicted")
+	}
+
+	var keys []int
+	for k := range c.All() {
+		keys = append(keys, k)
+	}
+	if fmt.Sprint(keys) != "[4 3 0]" {
+		t.Errorf("Bad order: %v", keys)
+	}
+
+	if !c.Del(4) || c.Del(4) || len(evicted) != 2 {
+		t.Error("Bad deletion")
+	}
+	if s := c.Stats(); s != (CacheStats{Hits: 1, Misses: 1, Evictions: 2}) {
+		t.Errorf("Bad stats: %+v", s)
+	}
+}
+
+func TestTTLCache(t *testing.T) {
+	now := time.Now()
+	expired := 0
+	c := NewHashTTLCache(time.Minute, func(IntHashable, int) { expired++ })
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 10; i++ {
+		c.Add(IntHashable(i), i)
+	}
+	c.AddWithTTL(42, 42, 0)
+	c.AddWithTTL(43, 43, time.Hour)
+
+	now = now.Add(time.Minute)
+	// lazy expiry
+	if _, ok := c.Get(0); ok || expired != 1 {
+		t.Error("Entry must be expired")
+	}
+	if c.Len() != 11 || c.Contains(1) {
+		t.Errorf("Bad length: %d", c.Len())
+	}
+	if n := c.Cleanup(); n != 9 || expired != 10 || c.Len() != 2 {
+		t.Errorf("Bad cleanup: %d", n)
+	}
+
+	now = now.Add(24 * time.Hour)
+	if v, ok := c.Get(42); !ok || v != 42 || c.Contains(43) {
+		t.Error("Entry without TTL must not expire")
+	}
+	if s := c.Stats(); s.Expirations != 10 || s.Hits != 1 || s.Misses != 1 {
+		t.Errorf("Bad stats: %+v", s)
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	now := time.Now()
+	var evicted []int
+	c := NewLRU(3, func(k int, _ int) { evicted = append(evicted, k) })
+	c.now = func() time.Time { return now }
+
+	c.Add(0, 0)
+	c.AddWithTTL(1, 1, time.Minute)
+	c.Add(2, 2)
+	now = now.Add(time.Minute)
+	// 1 expired so it is dropped instead of the least recently used 0
+	c.Add(3, 3)
+	if !c.Contains(0) || fmt.Sprint(evicted) != "[1]" {
+		t.Errorf("Bad evictions: %v", evicted)
+	}
+	c.Add(4, 4)
+	if c.Contains(0) || c.Len() != 3 {
+		t.Errorf("Least recently used entry must be evicted: %v", evicted)
+	}
+	if s := c.Stats(); s.Expirations != 1 || s.Evictions != 1 {
+		t.Errorf("Bad stats: %+v", s)
+	}
+}
+
+func TestSyncedCache(t *testing.T) {
+	c := NewSyncedCache(NewLRU[int, int](100, nil))
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.Add(i*1000+j, j)
+				c.Get(j)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if s := c.Stats(); c.Len() != 100 || s.Evictions != 9900 || s.Hits+s.Misses != 10000 {
+		t.Errorf("Bad stats: %+v", s)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ttl := NewSyncedCache(NewHashTTLCache[IntHashable, int](time.Millisecond, nil))
+	ttl.Add(1, 1)
+	ttl.CleanupEvery(ctx, time.Millisecond)
+	for deadline := time.Now().Add(5 * time.Second); ttl.Len() != 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("Expired entries not cleaned up")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
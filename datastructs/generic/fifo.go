@@ -0,0 +1,90 @@
+package generic
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Element is an element of a Fifo
+type Element[T any] struct {
+	Value T
+	Prev  *Element[T]
+	Next  *Element[T]
+}
+
+func (e *Element[T]) String() string {
+	return fmt.Sprintf("(%T(%v), %p, %p)", e.Value, e.Value, e.Prev, e.Next)
+}
+
+// Fifo is a thread safe first in first out queue, the zero value is an
+// empty Fifo ready to use
+type Fifo[T any] struct {
+	sync.RWMutex
+	e    *Element[T]
+	last *Element[T]
+	size int
+}
+
+// Push pushes i at the beginning of the Fifo
+func (f *Fifo[T]) Push(i T) {
+	f.Lock()
+	defer f.Unlock()
+	e := Element[T]{Value: i}
+	if f.e == nil {
+		f.e = &e
+		f.last = &e
+	} else {
+		e.Next = f.e
+		f.e.Prev = &e
+		f.e = &e
+	}
+	f.size++
+}
+
+func (f *Fifo[T]) String() string {
+	f.RLock()
+	defer f.RUnlock()
+	out := make([]string, 0)
+	for e := f.e; e != nil; e = e.Next {
+		out = append(out, e.String())
+	}
+	return strings.Join(out, "->")
+}
+
+// Empty returns true if the Fifo is empty
+func (f *Fifo[T]) Empty() bool {
+	f.RLock()
+	defer f.RUnlock()
+	return f.size == 0
+}
+
+// Pop pops the first element pushed into the Fifo, nil is returned if the
+// Fifo is empty
+func (f *Fifo[T]) Pop() *Element[T] {
+	f.Lock()
+	defer f.Unlock()
+	if f.last == nil {
+		return nil
+	}
+
+	popped := f.last
+	f.last = f.last.Prev
+	if f.last != nil {
+		f.last.Next = nil
+	}
+	// we have to nil out f.e if we pop
+	// the last element of the Fifo
+	if f.size == 1 {
+		f.e = nil
+	}
+	f.size--
+	return popped
+}
+
+// Len returns the number of elements in the Fifo
+func (f *Fifo[T]) Len() int {
+	f.RLock()
+	defer f.RUnlock()
+	return f.size
+}
@@ -0,0 +1,23 @@
+package generic
+
+import "testing"
+
+func TestFifo(t *testing.T) {
+	var f Fifo[int]
+	for loop := 0; loop < 2; loop++ {
+		for i := 0; i < 10; i++ {
+			f.Push(i)
+		}
+		if f.Len() != 10 {
+			t.Errorf("Bad length: %d", f.Len())
+		}
+		for i := 0; i < 10; i++ {
+			if e := f.Pop(); e == nil || e.Value != i {
+				t.Errorf("Bad element popped: %s", e)
+			}
+		}
+		if !f.Empty() || f.Pop() != nil {
+			t.Error("Fifo should be empty")
+		}
+	}
+}
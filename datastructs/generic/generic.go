@@ -0,0 +1,22 @@
+// Package generic provides type parameterized versions of the containers of
+// the datastructs package. The datastructs containers storing interface{}
+// are instances of those.
+package generic
+
+// Hashable is implemented by the keys of a HashMap
+type Hashable interface {
+	Hash() string
+}
+
+// feed returns a channel fed with the items of s by a goroutine, which
+// closes it once all the items are sent
+func feed[T any](s []T) chan T {
+	c := make(chan T)
+	go func() {
+		defer close(c)
+		for _, e := range s {
+			c <- e
+		}
+	}()
+	return c
+}
@@ -0,0 +1,251 @@
+package generic
+
+import (
+	"iter"
+	"sync"
+)
+
+// HashMap is a map whose keys are identified by their hash
+type HashMap[K Hashable, V any] struct {
+	keys   map[string]K
+	values map[string]V
+}
+
+// Item is a key, value pair of a HashMap
+type Item[K Hashable, V any] struct {
+	Key   K
+	Value V
+}
+
+// NewHashMap creates a new HashMap
+func NewHashMap[K Hashable, V any]() (hm *HashMap[K, V]) {
+	return &HashMap[K, V]{
+		make(map[string]K),
+		make(map[string]V),
+	}
+}
+
+// Contains returns true if the HashMap contains element referenced by key
+func (hm *HashMap[K, V]) Contains(h K) bool {
+	_, ok := hm.keys[h.Hash()]
+	return ok
+}
+
+// Get the element referenced by key in the HashMap
+func (hm *HashMap[K, V]) Get(h K) (v V, ok bool) {
+	if _, ok = hm.keys[h.Hash()]; ok {
+		v, ok = hm.values[h.Hash()]
+	}
+	return
+}
+
+// Add sets key, value in the map
+func (hm *HashMap[K, V]) Add(key K, value V) {
+	hm.keys[key.Hash()] = key
+	hm.values[key.Hash()] = value
+}
+
+// Del deletes the key and its associated value
+func (hm *HashMap[K, V]) Del(key K) {
+	delete(hm.keys, key.Hash())
+	delete(hm.values, key.Hash())
+}
+
+// Range calls fn for every key and value of the HashMap until fn returns
+// false
+func (hm *HashMap[K, V]) Range(fn func(K, V) bool) {
+	for h, k := range hm.keys {
+		if !fn(k, hm.values[h]) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over the keys and values of the HashMap
+func (hm *HashMap[K, V]) All() iter.Seq2[K, V] {
+	return hm.Range
+}
+
+// AllKeys returns an iterator over the keys of the HashMap
+func (hm *HashMap[K, V]) AllKeys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		hm.Range(func(k K, _ V) bool { return yield(k) })
+	}
+}
+
+// AllValues returns an iterator over the values of the HashMap
+func (hm *HashMap[K, V]) AllValues() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		hm.Range(func(_ K, v V) bool { return yield(v) })
+	}
+}
+
+// Keys returns a channel of Keys used by the HashMap
+//
+// Deprecated: the goroutine feeding the channel leaks if the channel is not
+// drained, use AllKeys instead.
+func (hm *HashMap[K, V]) Keys() (ch chan K) {
+	ch = make(chan K)
+	go func() {
+		defer close(ch)
+		for _, v := range hm.keys {
+			ch <- v
+		}
+	}()
+	return
+}
+
+// Values returns a channel of Values contained in the HashMap
+//
+// Deprecated: the goroutine feeding the channel leaks if the channel is not
+// drained, use AllValues instead.
+func (hm *HashMap[K, V]) Values() (ci chan V) {
+	ci = make(chan V)
+	go func() {
+		defer close(ci)
+		for _, v := range hm.values {
+			ci <- v
+		}
+	}()
+	return
+}
+
+// Items returns a channel of Item contained in the HashMap
+//
+// Deprecated: the goroutine feeding the channel leaks if the channel is not
+// drained, use Range or All instead.
+func (hm *HashMap[K, V]) Items() (ci chan Item[K, V]) {
+	ci = make(chan Item[K, V])
+	go func() {
+		defer close(ci)
+		for k := range hm.keys {
+			ci <- Item[K, V]{hm.keys[k], hm.values[k]}
+		}
+	}()
+	return
+}
+
+// Len returns the length of the HashMap
+func (hm *HashMap[K, V]) Len() int {
+	return len(hm.keys)
+}
+
+// SyncedHashMap is a thread safe HashMap
+type SyncedHashMap[K Hashable, V any] struct {
+	sync.RWMutex
+	m *HashMap[K, V]
+}
+
+// NewSyncedHashMap SyncedHashMap constructor
+func NewSyncedHashMap[K Hashable, V any]() (hm *SyncedHashMap[K, V]) {
+	return &SyncedHashMap[K, V]{m: NewHashMap[K, V]()}
+}
+
+// Contains returns true if the HashMap contains element referenced by key
+func (hm *SyncedHashMap[K, V]) Contains(key K) bool {
+	hm.RLock()
+	defer hm.RUnlock()
+	return hm.m.Contains(key)
+}
+
+// Get the element referenced by key in the HashMap
+func (hm *SyncedHashMap[K, V]) Get(key K) (V, bool) {
+	hm.RLock()
+	defer hm.RUnlock()
+	return hm.m.Get(key)
+}
+
+// Add sets key, value in the map
+func (hm *SyncedHashMap[K, V]) Add(key K, value V) {
+	hm.Lock()
+	defer hm.Unlock()
+	hm.m.Add(key, value)
+}
+
+// Del deletes the key and its associated value
+func (hm *SyncedHashMap[K, V]) Del(key K) {
+	hm.Lock()
+	defer hm.Unlock()
+	hm.m.Del(key)
+}
+
+// Range calls fn for every key and value of the HashMap until fn returns
+// false. The HashMap is read locked during the iteration so fn must not
+// modify it.
+func (hm *SyncedHashMap[K, V]) Range(fn func(K, V) bool) {
+	hm.RLock()
+	defer hm.RUnlock()
+	hm.m.Range(fn)
+}
+
+// All returns an iterator over the keys and values of the HashMap, which is
+// read locked during the iteration (c.f. Range)
+func (hm *SyncedHashMap[K, V]) All() iter.Seq2[K, V] {
+	return hm.Range
+}
+
+// AllKeys returns an iterator over the keys of the HashMap, which is read
+// locked during the iteration (c.f. Range)
+func (hm *SyncedHashMap[K, V]) AllKeys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		hm.Range(func(k K, _ V) bool { return yield(k) })
+	}
+}
+
+// AllValues returns an iterator over the values of the HashMap, which is
+// read locked during the iteration (c.f. Range)
+func (hm *SyncedHashMap[K, V]) AllValues() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		hm.Range(func(_ K, v V) bool { return yield(v) })
+	}
+}
+
+// Keys returns a channel of Keys used by the HashMap
+//
+// Deprecated: the goroutine feeding the channel leaks if the channel is not
+// drained, use AllKeys instead.
+func (hm *SyncedHashMap[K, V]) Keys() (ch chan K) {
+	hm.RLock()
+	defer hm.RUnlock()
+	// the channel is fed from a copy as the lock is released meanwhile
+	keys := make([]K, 0, hm.m.Len())
+	for k := range hm.m.AllKeys() {
+		keys = append(keys, k)
+	}
+	return feed(keys)
+}
+
+// Values returns a channel of Values contained in the HashMap
+//
+// Deprecated: the goroutine feeding the channel leaks if the channel is not
+// drained, use AllValues instead.
+func (hm *SyncedHashMap[K, V]) Values() (ci chan V) {
+	hm.RLock()
+	defer hm.RUnlock()
+	values := make([]V, 0, hm.m.Len())
+	for v := range hm.m.AllValues() {
+		values = append(values, v)
+	}
+	return feed(values)
+}
+
+// Items returns a channel of Item contained in the HashMap
+//
+// Deprecated: the goroutine feeding the channel leaks if the channel is not
+// drained, use Range or All instead.
+func (hm *SyncedHashMap[K, V]) Items() (ci chan Item[K, V]) {
+	hm.RLock()
+	defer hm.RUnlock()
+	items := make([]Item[K, V], 0, hm.m.Len())
+	for k, v := range hm.m.All() {
+		items = append(items, Item[K, V]{k, v})
+	}
+	return feed(items)
+}
+
+// Len returns the length of the HashMap
+func (hm *SyncedHashMap[K, V]) Len() int {
+	hm.RLock()
+	defer hm.RUnlock()
+	return hm.m.Len()
+}
@@ -0,0 +1,80 @@
+package generic
+
+import (
+	"fmt"
+	"testing"
+)
+
+type IntHashable int
+
+func (i IntHashable) Hash() string {
+	return fmt.Sprintf("%d", i)
+}
+
+func TestHashMap(t *testing.T) {
+	size := 1000
+	hm := NewSyncedHashMap[IntHashable, string]()
+	for i := 0; i < size; i++ {
+		hm.Add(IntHashable(i), fmt.Sprint(i))
+	}
+	if hm.Len() != size {
+		t.Error("Hashmap has wrong size")
+	}
+	if v, ok := hm.Get(42); !ok || v != "42" {
+		t.Errorf("Bad value: %q", v)
+	}
+
+	var even []IntHashable
+	for item := range hm.Items() {
+		if item.Key.Hash() != item.Value {
+			t.Error("Wrong item")
+		}
+		if item.Key%2 == 0 {
+			even = append(even, item.Key)
+		}
+	}
+	del := 0
+	for _, k := range even {
+		hm.Del(k)
+		del++
+	}
+	if hm.Len() != size-del || hm.Contains(42) {
+		t.Error("Hashmap has wrong size after deletions")
+	}
+	if v, ok := hm.Get(42); ok || v != "" {
+		t.Errorf("Deleted key must return zero value: %q", v)
+	}
+}
+
+func TestHashMapIteration(t *testing.T) {
+	hm := NewSyncedHashMap[IntHashable, int]()
+	for i := 0; i < 100; i++ {
+		hm.Add(IntHashable(i), i)
+	}
+
+	for k, v := range hm.All() {
+		if int(k) != v {
+			t.Errorf("Bad item %d: %d", k, v)
+		}
+	}
+	n := 0
+	for range hm.AllKeys() {
+		if n++; n == 10 {
+			break
+		}
+	}
+	sum := 0
+	for v := range hm.AllValues() {
+		sum += v
+	}
+	if n != 10 || sum != 4950 {
+		t.Errorf("Bad iteration: %d, %d", n, sum)
+	}
+
+	// the lock is released once Range returns
+	hm.Range(func(k IntHashable, _ int) bool { return k != 42 })
+	hm.Del(42)
+	if hm.Contains(42) {
+		t.Error("Key must be deleted")
+	}
+}
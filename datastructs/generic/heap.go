@@ -0,0 +1,336 @@
+package generic
+
+import (
+	"context"
+	"iter"
+	"sync"
+)
+
+// heap is the binary heap implementation shared by Heap and IndexedHeap
+type heap[T any] struct {
+	s    []T
+	less func(a, b T) bool
+	// moved is called with the elements moved and their new index, if not nil
+	moved func(e T, i int)
+}
+
+func (h *heap[T]) set(i int, e T) {
+	h.s[i] = e
+	if h.moved != nil {
+		h.moved(e, i)
+	}
+}
+
+func (h *heap[T]) swap(i, j int) {
+	ei, ej := h.s[i], h.s[j]
+	h.set(i, ej)
+	h.set(j, ei)
+}
+
+func (h *heap[T]) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !h.less(h.s[i], h.s[parent]) {
+			break
+		}
+		h.swap(i, parent)
+		i = parent
+	}
+}
+
+// down moves element i down the heap and returns true if it moved
+func (h *heap[T]) down(i int) bool {
+	start := i
+	for {
+		child := 2*i + 1
+		if child >= len(h.s) {
+			break
+		}
+		if right := child + 1; right < len(h.s) && h.less(h.s[right], h.s[child]) {
+			child = right
+		}
+		if !h.less(h.s[child], h.s[i]) {
+			break
+		}
+		h.swap(i, child)
+		i = child
+	}
+	return i > start
+}
+
+func (h *heap[T]) push(e T) {
+	h.s = append(h.s, e)
+	h.set(len(h.s)-1, e)
+	h.up(len(h.s) - 1)
+}
+
+func (h *heap[T]) fix(i int) {
+	if !h.down(i) {
+		h.up(i)
+	}
+}
+
+func (h *heap[T]) remove(i int) T {
+	var zero T
+	e, last := h.s[i], len(h.s)-1
+	if i != last {
+		h.swap(i, last)
+	}
+	// do not retain the element
+	h.s[last] = zero
+	h.s = h.s[:last]
+	if i != last {
+		h.fix(i)
+	}
+	return e
+}
+
+// Heap is a binary heap ordered by a less function, the smallest element
+// being popped first. Push and Pop are O(log n) unlike SortedSlice.Insert
+// which is O(n). It is not thread safe (c.f. SyncedHeap).
+type Heap[T any] struct {
+	h heap[T]
+}
+
+// NewHeap returns an empty heap ordered by less, which returns true if a is
+// less than b. Use a reversed less function to pop the greatest element
+// first.
+func NewHeap[T any](less func(a, b T) bool) *Heap[T] {
+	return &Heap[T]{heap[T]{less: less}}
+}
+
+// Push pushes e on the heap
+func (h *Heap[T]) Push(e T) {
+	h.h.push(e)
+}
+
+// Pop removes and returns the smallest element, ok being false if the heap
+// is empty
+func (h *Heap[T]) Pop() (e T, ok bool) {
+	if len(h.h.s) == 0 {
+		return
+	}
+	return h.h.remove(0), true
+}
+
+// Peek returns the smallest element without removing it, ok being false if
+// the heap is empty
+func (h *Heap[T]) Peek() (e T, ok bool) {
+	if len(h.h.s) == 0 {
+		return
+	}
+	return h.h.s[0], true
+}
+
+// Fix restores the order of the heap after the priority of element at index
+// i (c.f. Range) changed
+func (h *Heap[T]) Fix(i int) {
+	h.h.fix(i)
+}
+
+// Remove removes and returns the element at index i (c.f. Range)
+func (h *Heap[T]) Remove(i int) T {
+	return h.h.remove(i)
+}
+
+// Range calls fn with the index and the value of the elements, in no
+// particular order, until fn returns false. The heap must not be modified
+// during the iteration.
+func (h *Heap[T]) Range(fn func(int, T) bool) {
+	for i, e := range h.h.s {
+		if !fn(i, e) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over the elements of the heap (c.f. Range)
+func (h *Heap[T]) All() iter.Seq2[int, T] {
+	return h.Range
+}
+
+// Len returns the number of elements in the heap
+func (h *Heap[T]) Len() int {
+	return len(h.h.s)
+}
+
+// SyncedHeap is a thread safe Heap whose Pop waits for an element to be
+// pushed, which makes it suitable to feed workers, timers or schedulers
+type SyncedHeap[T any] struct {
+	sync.Mutex
+	h Heap[T]
+	// closed when an element is pushed, created by waiting Pops
+	pushed chan struct{}
+}
+
+// NewSyncedHeap returns an empty thread safe heap ordered by less (c.f.
+// NewHeap)
+func NewSyncedHeap[T any](less func(a, b T) bool) *SyncedHeap[T] {
+	return &SyncedHeap[T]{h: *NewHeap(less)}
+}
+
+// Push pushes e on the heap, waking up the waiting Pops
+func (s *SyncedHeap[T]) Push(e T) {
+	s.Lock()
+	defer s.Unlock()
+	s.h.Push(e)
+	signal(&s.pushed)
+}
+
+// Pop removes and returns the smallest element, waiting for an element to
+// be pushed if the heap is empty. It returns ctx.Err() if ctx is done
+// before.
+func (s *SyncedHeap[T]) Pop(ctx context.Context) (e T, err error) {
+	for {
+		s.Lock()
+		if e, ok := s.h.Pop(); ok {
+			s.Unlock()
+			return e, nil
+		}
+		if s.pushed == nil {
+			s.pushed = make(chan struct{})
+		}
+		pushed := s.pushed
+		s.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return e, ctx.Err()
+		case <-pushed:
+		}
+	}
+}
+
+// TryPop removes and returns the smallest element without waiting, ok being
+// false if the heap is empty
+func (s *SyncedHeap[T]) TryPop() (T, bool) {
+	s.Lock()
+	defer s.Unlock()
+	return s.h.Pop()
+}
+
+// Peek returns the smallest element without removing it, ok being false if
+// the heap is empty
+func (s *SyncedHeap[T]) Peek() (T, bool) {
+	s.Lock()
+	defer s.Unlock()
+	return s.h.Peek()
+}
+
+// Len returns the number of elements in the heap
+func (s *SyncedHeap[T]) Len() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.h.Len()
+}
+
+type indexedItem[K comparable, V any] struct {
+	key   K
+	value V
+	index int
+}
+
+// IndexedHeap is a binary heap of values identified by a key, allowing to
+// update or remove values by key in O(log n). It is not thread safe.
+type IndexedHeap[K comparable, V any] struct {
+	h     heap[*indexedItem[K, V]]
+	items map[K]*indexedItem[K, V]
+}
+
+// NewIndexedHeap returns an empty indexed heap ordered by less (c.f.
+// NewHeap)
+func NewIndexedHeap[K comparable, V any](less func(a, b V) bool) *IndexedHeap[K, V] {
+	return &IndexedHeap[K, V]{
+		h: heap[*indexedItem[K, V]]{
+			less:  func(a, b *indexedItem[K, V]) bool { return less(a.value, b.value) },
+			moved: func(item *indexedItem[K, V], i int) { item.index = i },
+		},
+		items: make(map[K]*indexedItem[K, V]),
+	}
+}
+
+// Push pushes value identified by key on the heap, updating the value if key
+// is already there
+func (h *IndexedHeap[K, V]) Push(key K, value V) {
+	if item, ok := h.items[key]; ok {
+		item.value = value
+		h.h.fix(item.index)
+		return
+	}
+	item := &indexedItem[K, V]{key: key, value: value}
+	h.items[key] = item
+	h.h.push(item)
+}
+
+// Update updates the value of key, returning false if key is not in the
+// heap
+func (h *IndexedHeap[K, V]) Update(key K, value V) bool {
+	if _, ok := h.items[key]; !ok {
+		return false
+	}
+	h.Push(key, value)
+	return true
+}
+
+// Fix restores the order of the heap after the priority of the value of key
+// changed, which is needed when values are pointers modified in place. It
+// returns false if key is not in the heap.
+func (h *IndexedHeap[K, V]) Fix(key K) bool {
+	item, ok := h.items[key]
+	if ok {
+		h.h.fix(item.index)
+	}
+	return ok
+}
+
+// Pop removes and returns the smallest value and its key, ok being false if
+// the heap is empty
+func (h *IndexedHeap[K, V]) Pop() (key K, value V, ok bool) {
+	if len(h.h.s) == 0 {
+		return
+	}
+	item := h.h.remove(0)
+	delete(h.items, item.key)
+	return item.key, item.value, true
+}
+
+// Peek returns the smallest value and its key without removing them, ok
+// being false if the heap is empty
+func (h *IndexedHeap[K, V]) Peek() (key K, value V, ok bool) {
+	if len(h.h.s) == 0 {
+		return
+	}
+	return h.h.s[0].key, h.h.s[0].value, true
+}
+
+// Get returns the value of key
+func (h *IndexedHeap[K, V]) Get(key K) (value V, ok bool) {
+	item, ok := h.items[key]
+	if ok {
+		value = item.value
+	}
+	return
+}
+
+// Contains returns true if key is in the heap
+func (h *IndexedHeap[K, V]) Contains(key K) bool {
+	_, ok := h.items[key]
+	return ok
+}
+
+// Remove removes key from the heap and returns its value, ok being false if
+// key is not in the heap
+func (h *IndexedHeap[K, V]) Remove(key K) (value V, ok bool) {
+	item, ok := h.items[key]
+	if !ok {
+		return
+	}
+	h.h.remove(item.index)
+	delete(h.items, key)
+	return item.value, true
+}
+
+// Len returns the number of values in the heap
+func (h *IndexedHeap[K, V]) Len() int {
+	return len(h.h.s)
+}
@@ -0,0 +1,157 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func intLess(a, b int) bool {
+	return a < b
+}
+
+// popAll pops all the elements of h, checking they are popped in order
+func popAll(t *testing.T, h *Heap[int]) (out []int) {
+	t.Helper()
+	for h.Len() > 0 {
+		e, _ := h.Pop()
+		if len(out) > 0 && e < out[len(out)-1] {
+			t.Fatalf("Bad order: %d after %d", e, out[len(out)-1])
+		}
+		out = append(out, e)
+	}
+	return
+}
+
+func TestHeap(t *testing.T) {
+	h := NewHeap(intLess)
+	if _, ok := h.Pop(); ok {
+		t.Error("Empty heap must not pop")
+	}
+	values := make([]int, 1000)
+	for i := range values {
+		values[i] = rand.Intn(100)
+		h.Push(values[i])
+	}
+	if e, _ := h.Peek(); e != 0 && h.Len() != len(values) {
+		t.Errorf("Bad peek: %d", e)
+	}
+	sort.Ints(values)
+	if out := popAll(t, h); len(out) != len(values) {
+		t.Errorf("Bad number of elements: %d", len(out))
+	}
+
+	// increase every multiple of ten and remove odd numbers
+	for i := 0; i < 100; i++ {
+		h.Push(i)
+	}
+	// indexes change when fixing or removing so iterations restart
+	for fixed := true; fixed; {
+		fixed = false
+		for i, e := range h.All() {
+			if e%10 == 0 && e < 1000 {
+				h.h.s[i] += 1000
+				h.Fix(i)
+				fixed = true
+				break
+			}
+		}
+	}
+	for removed := true; removed; {
+		removed = false
+		for i, e := range h.All() {
+			if e%2 == 1 {
+				h.Remove(i)
+				removed = true
+				break
+			}
+		}
+	}
+	out := popAll(t, h)
+	if len(out) != 50 || out[len(out)-1] != 1090 || out[0] != 2 {
+		t.Errorf("Bad elements: %v", out)
+	}
+}
+
+func TestSyncedHeap(t *testing.T) {
+	h := NewSyncedHeap(intLess)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := h.Pop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expecting deadline error: %v", err)
+	}
+
+	n, workers := 1000, 4
+	popped := make(chan int, n)
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				e, err := h.Pop(context.Background())
+				if err != nil || e == n {
+					return
+				}
+				popped <- e
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		h.Push(i)
+	}
+	for i := 0; i < workers; i++ {
+		h.Push(n)
+	}
+	wg.Wait()
+	close(popped)
+
+	seen := make(map[int]bool)
+	for e := range popped {
+		seen[e] = true
+	}
+	if len(seen) != n || h.Len() != 0 {
+		t.Errorf("Bad number of elements popped: %d", len(seen))
+	}
+}
+
+func TestIndexedHeap(t *testing.T) {
+	type task struct {
+		deadline int
+	}
+	h := NewIndexedHeap[string](func(a, b *task) bool { return a.deadline < b.deadline })
+	tasks := map[string]*task{"a": {5}, "b": {3}, "c": {8}, "d": {1}}
+	for k, v := range tasks {
+		h.Push(k, v)
+	}
+
+	tasks["c"].deadline = 0
+	if !h.Fix("c") || h.Fix("x") {
+		t.Error("Bad fix")
+	}
+	if !h.Update("a", &task{2}) || h.Update("x", &task{}) {
+		t.Error("Bad update")
+	}
+	if v, ok := h.Remove("d"); !ok || v.deadline != 1 || h.Contains("d") {
+		t.Error("Bad removal")
+	}
+	if v, ok := h.Get("b"); !ok || v.deadline != 3 {
+		t.Error("Bad get")
+	}
+	if k, _, _ := h.Peek(); k != "c" {
+		t.Errorf("Bad peek: %s", k)
+	}
+
+	var order string
+	for h.Len() > 0 {
+		k, _, _ := h.Pop()
+		order += k
+	}
+	if order != "cab" || h.Contains("c") {
+		t.Errorf("Bad order: %s", order)
+	}
+}
@@ -0,0 +1,271 @@
+package generic
+
+import (
+	"cmp"
+	"iter"
+	"math/bits"
+	"math/rand/v2"
+)
+
+const (
+	// maxLevel is enough for 2^64 elements with a level probability of 1/4
+	maxLevel = 32
+)
+
+type orderedNode[K, V any] struct {
+	key   K
+	value V
+	// previous node on the lowest level, nil for the first node
+	prev *orderedNode[K, V]
+	next []*orderedNode[K, V]
+}
+
+// OrderedMap is a map whose keys are kept ordered by a comparison function,
+// implemented as a skip list. Lookups, insertions and deletions are
+// O(log n) on average, and iterations can start from any key in both
+// directions. It is not thread safe.
+type OrderedMap[K, V any] struct {
+	compare func(a, b K) int
+	head    orderedNode[K, V]
+	tail    *orderedNode[K, V]
+	// number of levels in use
+	level int
+	len   int
+}
+
+// NewOrderedMap returns an empty map ordered by compare, which returns a
+// negative number if a is less than b, zero if they are equal and a positive
+// number otherwise (c.f. cmp.Compare)
+func NewOrderedMap[K, V any](compare func(a, b K) int) *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{
+		compare: compare,
+		head:    orderedNode[K, V]{next: make([]*orderedNode[K, V], maxLevel)},
+	}
+}
+
+// NewOrderedMapOf returns an empty map ordered by the natural order of the
+// keys
+func NewOrderedMapOf[K cmp.Ordered, V any]() *OrderedMap[K, V] {
+	return NewOrderedMap[K, V](cmp.Compare[K])
+}
+
+func randomLevel() int {
+	// each level is used with a probability of 1/4
+	return min(bits.TrailingZeros64(rand.Uint64())/2+1, maxLevel)
+}
+
+// search returns the last node whose key is less than key, and fills update
+// if not nil with the last node of each level whose key is less than key
+func (m *OrderedMap[K, V]) search(key K, update []*orderedNode[K, V]) *orderedNode[K, V] {
+	n := &m.head
+	for level := m.level - 1; level >= 0; level-- {
+		for next := n.next[level]; next != nil && m.compare(next.key, key) < 0; next = n.next[level] {
+			n = next
+		}
+		if update != nil {
+			update[level] = n
+		}
+	}
+	return n
+}
+
+// ceiling returns the first node whose key is greater than or equal to key
+func (m *OrderedMap[K, V]) ceiling(key K) *orderedNode[K, V] {
+	return m.search(key, nil).next[0]
+}
+
+// floor returns the last node whose key is less than or equal to key
+func (m *OrderedMap[K, V]) floor(key K) *orderedNode[K, V] {
+	n := m.search(key, nil)
+	if next := n.next[0]; next != nil && m.compare(next.key, key) == 0 {
+		return next
+	}
+	if n == &m.head {
+		return nil
+	}
+	return n
+}
+
+// find returns the node of key or nil
+func (m *OrderedMap[K, V]) find(key K) *orderedNode[K, V] {
+	if n := m.ceiling(key); n != nil && m.compare(n.key, key) == 0 {
+		return n
+	}
+	return nil
+}
+
+// Add adds or updates key with value
+func (m *OrderedMap[K, V]) Add(key K, value V) {
+	var update [maxLevel]*orderedNode[K, V]
+	prev := m.search(key, update[:])
+	if n := prev.next[0]; n != nil && m.compare(n.key, key) == 0 {
+		n.value = value
+		return
+	}
+
+	n := &orderedNode[K, V]{key: key, value: value, next: make([]*orderedNode[K, V], randomLevel())}
+	for ; m.level < len(n.next); m.level++ {
+		update[m.level] = &m.head
+	}
+	for level := range n.next {
+		n.next[level] = update[level].next[level]
+		update[level].next[level] = n
+	}
+	if prev != &m.head {
+		n.prev = prev
+	}
+	if n.next[0] != nil {
+		n.next[0].prev = n
+	} else {
+		m.tail = n
+	}
+	m.len++
+}
+
+// Get returns the value of key
+func (m *OrderedMap[K, V]) Get(key K) (value V, ok bool) {
+	if n := m.find(key); n != nil {
+		return n.value, true
+	}
+	return
+}
+
+// Contains returns true if key is in the map
+func (m *OrderedMap[K, V]) Contains(key K) bool {
+	return m.find(key) != nil
+}
+
+// Del deletes key from the map and returns its value, ok being false if key
+// was not in the map
+func (m *OrderedMap[K, V]) Del(key K) (value V, ok bool) {
+	var update [maxLevel]*orderedNode[K, V]
+	n := m.search(key, update[:]).next[0]
+	if n == nil || m.compare(n.key, key) != 0 {
+		return
+	}
+	for level := range n.next {
+		update[level].next[level] = n.next[level]
+	}
+	if n.next[0] != nil {
+		n.next[0].prev = n.prev
+	} else {
+		m.tail = n.prev
+	}
+	for m.level > 0 && m.head.next[m.level-1] == nil {
+		m.level--
+	}
+	m.len--
+	return n.value, true
+}
+
+// nodeItem returns the key and value of n, ok being false if n is nil
+func nodeItem[K, V any](n *orderedNode[K, V]) (key K, value V, ok bool) {
+	if n == nil {
+		return
+	}
+	return n.key, n.value, true
+}
+
+// Min returns the smallest key and its value, ok being false if the map is
+// empty
+func (m *OrderedMap[K, V]) Min() (K, V, bool) {
+	return nodeItem(m.head.next[0])
+}
+
+// Max returns the greatest key and its value, ok being false if the map is
+// empty
+func (m *OrderedMap[K, V]) Max() (K, V, bool) {
+	return nodeItem(m.tail)
+}
+
+// Floor returns the greatest key less than or equal to key and its value,
+// ok being false if there is none
+func (m *OrderedMap[K, V]) Floor(key K) (K, V, bool) {
+	return nodeItem(m.floor(key))
+}
+
+// Ceiling returns the smallest key greater than or equal to key and its
+// value, ok being false if there is none
+func (m *OrderedMap[K, V]) Ceiling(key K) (K, V, bool) {
+	return nodeItem(m.ceiling(key))
+}
+
+// Range calls fn with the items of the map in ascending order of the keys
+// until fn returns false. The map must not be modified during the
+// iteration.
+func (m *OrderedMap[K, V]) Range(fn func(K, V) bool) {
+	for n := m.head.next[0]; n != nil; n = n.next[0] {
+		if !fn(n.key, n.value) {
+			return
+		}
+	}
+}
+
+// ReversedRange calls fn with the items of the map in descending order of
+// the keys until fn returns false (c.f. Range)
+func (m *OrderedMap[K, V]) ReversedRange(fn func(K, V) bool) {
+	for n := m.tail; n != nil; n = n.prev {
+		if !fn(n.key, n.value) {
+			return
+		}
+	}
+}
+
+// RangeBetween calls fn with the items whose keys are greater than or equal
+// to lo and less than hi, in ascending order of the keys, until fn returns
+// false (c.f. Range)
+func (m *OrderedMap[K, V]) RangeBetween(lo, hi K, fn func(K, V) bool) {
+	for n := m.ceiling(lo); n != nil && m.compare(n.key, hi) < 0; n = n.next[0] {
+		if !fn(n.key, n.value) {
+			return
+		}
+	}
+}
+
+// ReversedRangeBetween calls fn with the items whose keys are greater than
+// or equal to lo and less than hi, in descending order of the keys, until fn
+// returns false (c.f. Range)
+func (m *OrderedMap[K, V]) ReversedRangeBetween(lo, hi K, fn func(K, V) bool) {
+	n := m.search(hi, nil)
+	if n == &m.head {
+		return
+	}
+	for ; n != nil && m.compare(n.key, lo) >= 0; n = n.prev {
+		if !fn(n.key, n.value) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over the items of the map in ascending order of
+// the keys (c.f. Range)
+func (m *OrderedMap[K, V]) All() iter.Seq2[K, V] {
+	return m.Range
+}
+
+// Backward returns an iterator over the items of the map in descending
+// order of the keys (c.f. ReversedRange)
+func (m *OrderedMap[K, V]) Backward() iter.Seq2[K, V] {
+	return m.ReversedRange
+}
+
+// Between returns an iterator over the items whose keys are in [lo, hi) in
+// ascending order of the keys (c.f. RangeBetween)
+func (m *OrderedMap[K, V]) Between(lo, hi K) iter.Seq2[K, V] {
+	return func(fn func(K, V) bool) {
+		m.RangeBetween(lo, hi, fn)
+	}
+}
+
+// BackwardBetween returns an iterator over the items whose keys are in
+// [lo, hi) in descending order of the keys (c.f. ReversedRangeBetween)
+func (m *OrderedMap[K, V]) BackwardBetween(lo, hi K) iter.Seq2[K, V] {
+	return func(fn func(K, V) bool) {
+		m.ReversedRangeBetween(lo, hi, fn)
+	}
+}
+
+// Len returns the number of items in the map
+func (m *OrderedMap[K, V]) Len() int {
+	return m.len
+}
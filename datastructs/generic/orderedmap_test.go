@@ -0,0 +1,105 @@
+package generic
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestOrderedMap(t *testing.T) {
+	m := NewOrderedMapOf[int, string]()
+	if _, _, ok := m.Min(); ok {
+		t.Error("Empty map must have no minimum")
+	}
+
+	ref := make(map[int]bool)
+	for i := 0; i < 10000; i++ {
+		k := rand.Intn(5000) * 2
+		m.Add(k, "value")
+		ref[k] = true
+		if i%3 == 0 {
+			k = rand.Intn(5000) * 2
+			if _, ok := m.Del(k); ok != ref[k] {
+				t.Fatalf("Bad deletion of %d", k)
+			}
+			delete(ref, k)
+		}
+	}
+	keys := make([]int, 0, len(ref))
+	for k := range ref {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	if m.Len() != len(keys) {
+		t.Errorf("Bad length: %d instead of %d", m.Len(), len(keys))
+	}
+	var got []int
+	for k := range m.All() {
+		got = append(got, k)
+	}
+	if !slices.Equal(got, keys) {
+		t.Error("Bad ascending order")
+	}
+	got = got[:0]
+	for k := range m.Backward() {
+		got = append(got, k)
+	}
+	slices.Reverse(got)
+	if !slices.Equal(got, keys) {
+		t.Error("Bad descending order")
+	}
+
+	for _, k := range keys[:100] {
+		if v, ok := m.Get(k); !ok || v != "value" || !m.Contains(k) || m.Contains(k+1) {
+			t.Fatalf("Bad lookup of %d", k)
+		}
+		if f, _, ok := m.Floor(k + 1); !ok || f != k {
+			t.Errorf("Bad floor of %d: %d", k+1, f)
+		}
+		if c, _, ok := m.Ceiling(k - 1); !ok || c != k {
+			t.Errorf("Bad ceiling of %d: %d", k-1, c)
+		}
+	}
+	if k, _, _ := m.Min(); k != keys[0] {
+		t.Errorf("Bad minimum: %d", k)
+	}
+	if k, _, _ := m.Max(); k != keys[len(keys)-1] {
+		t.Errorf("Bad maximum: %d", k)
+	}
+	if _, _, ok := m.Floor(keys[0] - 1); ok {
+		t.Error("There must be no floor before the minimum")
+	}
+	if _, _, ok := m.Ceiling(keys[len(keys)-1] + 1); ok {
+		t.Error("There must be no ceiling after the maximum")
+	}
+}
+
+func TestOrderedMapBetween(t *testing.T) {
+	m := NewOrderedMap[int, int](func(a, b int) int { return a - b })
+	for i := 0; i < 100; i += 10 {
+		m.Add(i, i*i)
+	}
+
+	var got []int
+	for k, v := range m.Between(15, 50) {
+		if v != k*k {
+			t.Errorf("Bad value of %d: %d", k, v)
+		}
+		got = append(got, k)
+	}
+	for k := range m.BackwardBetween(15, 50) {
+		got = append(got, k)
+	}
+	for k := range m.BackwardBetween(-10, 0) {
+		got = append(got, k)
+	}
+	for k := range m.Between(0, 100) {
+		if got = append(got, k); k == 20 {
+			break
+		}
+	}
+	if expected := []int{20, 30, 40, 40, 30, 20, 0, 10, 20}; !slices.Equal(got, expected) {
+		t.Errorf("Bad range: %v", got)
+	}
+}
@@ -0,0 +1,231 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQueueClosed is returned when pushing to a closed Queue or popping
+	// from a closed and drained Queue
+	ErrQueueClosed = errors.New("Queue closed")
+	// ErrQueueFull is returned by TryPush when the Queue is full
+	ErrQueueFull = errors.New("Queue full")
+	// ErrQueueEmpty is returned by TryPop when the Queue is empty
+	ErrQueueEmpty = errors.New("Queue empty")
+)
+
+// QueueStats holds the counters of a Queue, waits and rejections showing
+// the backpressure between producers and consumers
+type QueueStats struct {
+	Pushed uint64
+	Popped uint64
+	// PushWaits is the number of pushes which waited for the queue not to be
+	// full and PushWaitTime the total time spent waiting
+	PushWaits    uint64
+	PushWaitTime time.Duration
+	// PopWaits is the number of pops which waited for the queue not to be
+	// empty and PopWaitTime the total time spent waiting
+	PopWaits    uint64
+	PopWaitTime time.Duration
+	// Rejected is the number of TryPush which failed because the queue was
+	// full
+	Rejected uint64
+	// MaxLen is the highest number of elements the queue held
+	MaxLen int
+}
+
+// Queue is a thread safe bounded first in first out queue for multiple
+// producers and consumers. Pushes wait while the queue is full and pops
+// while it is empty. Once closed, pushes fail and pops drain the remaining
+// elements.
+type Queue[T any] struct {
+	sync.Mutex
+	buf    []T
+	head   int
+	len    int
+	closed bool
+	// closed when the queue is not full or not empty anymore, created by
+	// waiting pushes and pops
+	notFull  chan struct{}
+	notEmpty chan struct{}
+	stats    QueueStats
+}
+
+// NewQueue returns an empty Queue holding at most capacity elements
+func NewQueue[T any](capacity int) *Queue[T] {
+	if capacity < 1 {
+		panic("queue capacity must be positive")
+	}
+	return &Queue[T]{buf: make([]T, capacity)}
+}
+
+// signal wakes up the goroutines waiting on ch
+func signal(ch *chan struct{}) {
+	if *ch != nil {
+		close(*ch)
+		*ch = nil
+	}
+}
+
+// wait waits until ready returns true or ctx is done, waiting on ch to be
+// signaled between checks. It must be called with q locked and returns with
+// q locked.
+func (q *Queue[T]) wait(ctx context.Context, ready func() bool, ch *chan struct{}, waits *uint64, total *time.Duration) error {
+	if ready() {
+		return nil
+	}
+	start := time.Now()
+	*waits++
+	defer func() { *total += time.Since(start) }()
+	for !ready() {
+		if *ch == nil {
+			*ch = make(chan struct{})
+		}
+		signaled := *ch
+		q.Unlock()
+		select {
+		case <-ctx.Done():
+			q.Lock()
+			return ctx.Err()
+		case <-signaled:
+		}
+		q.Lock()
+	}
+	return nil
+}
+
+func (q *Queue[T]) push(e T) {
+	q.buf[(q.head+q.len)%len(q.buf)] = e
+	q.len++
+	q.stats.Pushed++
+	q.stats.MaxLen = max(q.stats.MaxLen, q.len)
+	signal(&q.notEmpty)
+}
+
+func (q *Queue[T]) pop() T {
+	var zero T
+	e := q.buf[q.head]
+	// do not retain the element
+	q.buf[q.head] = zero
+	q.head = (q.head + 1) % len(q.buf)
+	q.len--
+	q.stats.Popped++
+	signal(&q.notFull)
+	return e
+}
+
+// PushWait pushes e, waiting for the queue not to be full. It returns
+// ErrQueueClosed if the queue is closed and ctx.Err() if ctx is done before
+// e is pushed.
+func (q *Queue[T]) PushWait(ctx context.Context, e T) error {
+	q.Lock()
+	defer q.Unlock()
+	ready := func() bool { return q.closed || q.len < len(q.buf) }
+	if err := q.wait(ctx, ready, &q.notFull, &q.stats.PushWaits, &q.stats.PushWaitTime); err != nil {
+		return err
+	}
+	if q.closed {
+		return ErrQueueClosed
+	}
+	q.push(e)
+	return nil
+}
+
+// TryPush pushes e without waiting. It returns ErrQueueFull if the queue is
+// full and ErrQueueClosed if it is closed.
+func (q *Queue[T]) TryPush(e T) error {
+	q.Lock()
+	defer q.Unlock()
+	switch {
+	case q.closed:
+		return ErrQueueClosed
+	case q.len == len(q.buf):
+		q.stats.Rejected++
+		return ErrQueueFull
+	}
+	q.push(e)
+	return nil
+}
+
+// PopWait pops the oldest element, waiting for the queue not to be empty.
+// It returns ErrQueueClosed if the queue is closed and drained and
+// ctx.Err() if ctx is done before an element is popped.
+func (q *Queue[T]) PopWait(ctx context.Context) (e T, err error) {
+	q.Lock()
+	defer q.Unlock()
+	ready := func() bool { return q.closed || q.len > 0 }
+	if err = q.wait(ctx, ready, &q.notEmpty, &q.stats.PopWaits, &q.stats.PopWaitTime); err != nil {
+		return
+	}
+	if q.len == 0 {
+		return e, ErrQueueClosed
+	}
+	return q.pop(), nil
+}
+
+// TryPop pops the oldest element without waiting. It returns ErrQueueEmpty
+// if the queue is empty and ErrQueueClosed if it is closed and drained.
+func (q *Queue[T]) TryPop() (e T, err error) {
+	q.Lock()
+	defer q.Unlock()
+	switch {
+	case q.len > 0:
+		return q.pop(), nil
+	case q.closed:
+		return e, ErrQueueClosed
+	}
+	return e, ErrQueueEmpty
+}
+
+// Drain returns an iterator popping the elements until the queue is closed
+// and drained or ctx is done
+func (q *Queue[T]) Drain(ctx context.Context) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for {
+			e, err := q.PopWait(ctx)
+			if err != nil || !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// Close closes the queue, waking up the waiting pushes and pops. Elements
+// already pushed can still be popped. Closing a closed queue does nothing.
+func (q *Queue[T]) Close() {
+	q.Lock()
+	defer q.Unlock()
+	q.closed = true
+	signal(&q.notFull)
+	signal(&q.notEmpty)
+}
+
+// Closed returns true if the queue is closed
+func (q *Queue[T]) Closed() bool {
+	q.Lock()
+	defer q.Unlock()
+	return q.closed
+}
+
+// Len returns the number of elements in the queue
+func (q *Queue[T]) Len() int {
+	q.Lock()
+	defer q.Unlock()
+	return q.len
+}
+
+// Cap returns the capacity of the queue
+func (q *Queue[T]) Cap() int {
+	return len(q.buf)
+}
+
+// Stats returns the counters of the queue
+func (q *Queue[T]) Stats() QueueStats {
+	q.Lock()
+	defer q.Unlock()
+	return q.stats
+}
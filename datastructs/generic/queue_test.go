@@ -0,0 +1,90 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQueue(t *testing.T) {
+	q := NewQueue[int](10)
+	if _, err := q.TryPop(); !errors.Is(err, ErrQueueEmpty) {
+		t.Errorf("Expecting empty queue error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.PopWait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expecting deadline error: %v", err)
+	}
+
+	// producers are faster than consumers so they wait
+	n, producers, consumers := 1000, 4, 2
+	popped := make(chan int, n*producers)
+	wg := sync.WaitGroup{}
+	for i := 0; i < consumers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range q.Drain(context.Background()) {
+				popped <- e
+				time.Sleep(time.Microsecond)
+			}
+		}()
+	}
+	pwg := sync.WaitGroup{}
+	for i := 0; i < producers; i++ {
+		pwg.Add(1)
+		go func(i int) {
+			defer pwg.Done()
+			for j := 0; j < n; j++ {
+				if err := q.PushWait(context.Background(), i*n+j); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	pwg.Wait()
+	q.Close()
+	wg.Wait()
+	close(popped)
+
+	seen := make(map[int]bool)
+	for e := range popped {
+		seen[e] = true
+	}
+	if len(seen) != n*producers {
+		t.Errorf("Bad number of elements popped: %d", len(seen))
+	}
+
+	stats := q.Stats()
+	t.Logf("%+v", stats)
+	if stats.Pushed != uint64(n*producers) || stats.Popped != stats.Pushed || stats.PushWaits == 0 || stats.MaxLen != q.Cap() {
+		t.Errorf("Bad stats: %+v", stats)
+	}
+	if err := q.PushWait(context.Background(), 0); !errors.Is(err, ErrQueueClosed) || !q.Closed() {
+		t.Errorf("Expecting closed queue error: %v", err)
+	}
+}
+
+func TestQueueClose(t *testing.T) {
+	q := NewQueue[int](1)
+	q.TryPush(1)
+	done := make(chan error)
+	go func() {
+		// waits until the queue is closed
+		done <- q.PushWait(context.Background(), 2)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q.Close()
+	if err := <-done; !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Expecting closed queue error: %v", err)
+	}
+	if e, err := q.TryPop(); err != nil || e != 1 {
+		t.Errorf("Closed queue must be drained: %d, %v", e, err)
+	}
+	if err := q.TryPush(3); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Expecting closed queue error: %v", err)
+	}
+}
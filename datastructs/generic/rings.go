@@ -0,0 +1,197 @@
+package generic
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RingSet is a RingSlice containing unique items
+type RingSet[T comparable] struct {
+	rslice *RingSlice[T]
+	set    *Set[T]
+}
+
+// NewRingSet creates a new RingSet of len items
+func NewRingSet[T comparable](len int) *RingSet[T] {
+	return &RingSet[T]{NewRingSlice[T](len), NewSet[T]()}
+}
+
+func (r RingSet[T]) String() string {
+	return r.rslice.String()
+}
+
+// Contains returns true if the RingSet contains all the items
+func (r *RingSet[T]) Contains(item ...T) bool {
+	return r.set.Contains(item...)
+}
+
+// Add adds item to the RingSet if not already there, replacing the oldest
+// item when the RingSet is full
+func (r *RingSet[T]) Add(item T) {
+	// we add item only if not already there
+	if !r.Contains(item) {
+		// we delete item only if RingSet is full
+		if r.rslice.Full() {
+			// delete the item which is going to be erased
+			r.set.Del(r.rslice.Oldest())
+		}
+		r.rslice.Add(item)
+		r.set.Add(item)
+	}
+}
+
+// Len returns the size of the RingSet
+func (r *RingSet[T]) Len() int {
+	return r.rslice.Len()
+}
+
+// GetItem returns the item at index i
+func (r *RingSet[T]) GetItem(i int) T {
+	return r.rslice.GetItem(i)
+}
+
+// SetItem sets the item at index i
+func (r *RingSet[T]) SetItem(i int, item T) {
+	r.rslice.SetItem(i, item)
+	r.set.Add(item)
+}
+
+// Slice returns a copy of the underlying slice
+func (r *RingSet[T]) Slice() []T {
+	return r.rslice.Slice()
+}
+
+// Copy returns a copy of the RingSet
+func (r *RingSet[T]) Copy() *RingSet[T] {
+	new := NewRingSet[T](r.Len())
+	new.rslice = r.rslice.Copy()
+	new.set = r.set.Copy()
+	return new
+}
+
+// RingSlice returns a copy of the RingSlice holding the items
+func (r *RingSet[T]) RingSlice() *RingSlice[T] {
+	return r.rslice.Copy()
+}
+
+// Set returns a copy of the Set holding the items
+func (r *RingSet[T]) Set() *Set[T] {
+	return r.set.Copy()
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (r *RingSet[T]) UnmarshalJSON(data []byte) (err error) {
+	if err = json.Unmarshal(data, &r.rslice); err != nil {
+		return
+	}
+	r.set = NewInitSet(r.rslice.Slice()...)
+	return
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (r *RingSet[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&(r.rslice))
+}
+
+// RingSlice is a fixed size slice whose oldest items are replaced by the
+// new ones
+type RingSlice[T any] struct {
+	ring   []T
+	cursor int
+	full   bool
+}
+
+// NewRingSlice creates a new RingSlice of len items
+func NewRingSlice[T any](len int) *RingSlice[T] {
+	return &RingSlice[T]{make([]T, len), 0, false}
+}
+
+func (r *RingSlice[T]) incCursor() {
+	r.cursor = r.nextCursor()
+	if r.cursor == 0 {
+		r.full = true
+	}
+}
+
+func (r *RingSlice[T]) nextCursor() int {
+	if r.cursor < len(r.ring)-1 {
+		return r.cursor + 1
+	}
+	return 0
+}
+
+func (r RingSlice[T]) String() string {
+	return fmt.Sprintf("%v", r.ring)
+}
+
+// Add adds item to the RingSlice, replacing the oldest item when it is full
+func (r *RingSlice[T]) Add(item T) {
+	if r.cursor < len(r.ring) {
+		r.ring[r.cursor] = item
+		r.incCursor()
+	}
+}
+
+// Full returns true once all the items of the RingSlice are set, new items
+// replacing the oldest ones
+func (r *RingSlice[T]) Full() bool {
+	return r.full
+}
+
+// Oldest returns the oldest item of a full RingSlice, which is the next one
+// to be replaced
+func (r *RingSlice[T]) Oldest() T {
+	return r.ring[r.cursor]
+}
+
+// Len returns the size of the RingSlice
+func (r *RingSlice[T]) Len() int {
+	return len(r.ring)
+}
+
+// GetItem returns the item at index i
+func (r *RingSlice[T]) GetItem(i int) T {
+	return r.ring[i]
+}
+
+// SetItem sets the item at index i
+func (r *RingSlice[T]) SetItem(i int, item T) {
+	r.ring[i] = item
+}
+
+// Slice returns a copy of the underlying slice
+func (r *RingSlice[T]) Slice() []T {
+	l := make([]T, len(r.ring))
+	copy(l, r.ring)
+	return l
+}
+
+// Copy returns a copy of the RingSlice
+func (r *RingSlice[T]) Copy() *RingSlice[T] {
+	new := NewRingSlice[T](r.Len())
+	copy(new.ring, r.ring)
+	new.cursor = r.cursor
+	new.full = r.full
+	return new
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (r *RingSlice[T]) UnmarshalJSON(data []byte) (err error) {
+	r.cursor = 0
+	return json.Unmarshal(data, &r.ring)
+}
+
+// MarshalJSON implements json.Marshaler interface, items are serialized
+// from the oldest to the newest
+func (r *RingSlice[T]) MarshalJSON() ([]byte, error) {
+	s := make([]T, len(r.ring))
+	if len(s) > 1 {
+		p1 := r.ring[r.cursor:len(r.ring)]
+		p2 := r.ring[0:r.cursor]
+		copy(s, p1)
+		copy(s[len(p1):], p2)
+	} else {
+		copy(s, r.ring)
+	}
+	return json.Marshal(s)
+}
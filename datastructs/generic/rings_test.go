@@ -0,0 +1,59 @@
+package generic
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRingSlice(t *testing.T) {
+	r := NewRingSlice[int](10)
+	for i := 0; i < 11; i++ {
+		r.Add(i)
+	}
+	if !r.Full() || r.GetItem(0) != 10 || r.Oldest() != 1 {
+		t.Errorf("Bad ring slice: %s", r)
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nr := NewRingSlice[int](0)
+	if err := json.Unmarshal(b, nr); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < nr.Len(); i++ {
+		if nr.GetItem(i) != i+1 {
+			t.Errorf("Bad item %d: %d", i, nr.GetItem(i))
+		}
+	}
+}
+
+func TestRingSet(t *testing.T) {
+	r := NewRingSet[int](10)
+	for i := 0; i < 100; i++ {
+		r.Add(i)
+		r.Add(i)
+	}
+	for i := 0; i < 100; i++ {
+		if r.Contains(i) != (i >= 90) {
+			t.Errorf("Bad presence of %d", i)
+		}
+	}
+	if r.Set().Len() != r.Len() {
+		t.Error("Inconsistent size")
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nr := NewRingSet[int](0)
+	if err := json.Unmarshal(b, nr); err != nil {
+		t.Fatal(err)
+	}
+	// integers are not unmarshalled as float64
+	if !nr.Contains(90, 99) || nr.Contains(89) {
+		t.Errorf("Bad unmarshalled ring set: %s", nr)
+	}
+}
@@ -0,0 +1,281 @@
+package generic
+
+import (
+	"encoding/json"
+	"iter"
+	"sort"
+	"sync"
+)
+
+// Set datastruct that represent a set
+type Set[T comparable] struct {
+	i   uint
+	set map[T]uint
+}
+
+// NewSet constructs a new Set containing the data of sets
+func NewSet[T comparable](sets ...*Set[T]) *Set[T] {
+	s := &Set[T]{0, make(map[T]uint)}
+	for _, set := range sets {
+		s.Add(set.SortSlice()...)
+	}
+	return s
+}
+
+// NewInitSet constructs a new Set initialized with data
+func NewInitSet[T comparable](data ...T) *Set[T] {
+	s := NewSet[T]()
+	s.Add(data...)
+	return s
+}
+
+// Equal returns true if both sets are equal
+func (s *Set[T]) Equal(other *Set[T]) bool {
+	if s.Len() != other.Len() {
+		return false
+	}
+	for key := range s.set {
+		if !other.Contains(key) {
+			return false
+		}
+	}
+	return true
+}
+
+// Copy returns a copy of the current set
+func (s *Set[T]) Copy() *Set[T] {
+	return NewSet(s)
+}
+
+// Add adds data to the set
+func (s *Set[T]) Add(data ...T) {
+	for _, data := range data {
+		s.set[data] = s.i
+		s.i++
+	}
+}
+
+// Del deletes data from the set
+func (s *Set[T]) Del(data ...T) {
+	for _, data := range data {
+		delete(s.set, data)
+	}
+}
+
+// Intersect returns a pointer to a new set containing the intersection of current
+// set and other
+func (s *Set[T]) Intersect(other *Set[T]) *Set[T] {
+	newSet := NewSet[T]()
+	for _, k := range s.SortSlice() {
+		if other.Contains(k) {
+			newSet.Add(k)
+		}
+	}
+	return newSet
+}
+
+// Union returns a pointer to a new set containing the union of current set and other
+func (s *Set[T]) Union(other *Set[T]) *Set[T] {
+	newSet := NewSet(s)
+	newSet.Add(other.SortSlice()...)
+	return newSet
+}
+
+// Contains returns true if the set contains all the data
+func (s *Set[T]) Contains(data ...T) bool {
+	for _, data := range data {
+		if _, ok := s.set[data]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// SortSlice returns a new slice containing  the data in the set
+// sorted by order of insertion.
+func (s *Set[T]) SortSlice() []T {
+	out := s.Slice()
+	sort.Slice(out, func(i, j int) bool {
+		return s.set[out[i]] < s.set[out[j]]
+	})
+	return out
+}
+
+// Slice returns a pointer to a new slice containing the data in the set
+func (s *Set[T]) Slice() []T {
+	out := make([]T, 0, s.Len())
+	for key := range s.set {
+		out = append(out, key)
+	}
+	return out
+}
+
+// Range calls fn for every element of the set until fn returns false
+func (s *Set[T]) Range(fn func(T) bool) {
+	for k := range s.set {
+		if !fn(k) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over the elements of the set
+func (s *Set[T]) All() iter.Seq[T] {
+	return s.Range
+}
+
+// Items returns a channel with all the elements contained in the set
+//
+// Deprecated: the goroutine feeding the channel leaks if the channel is not
+// drained, use Range or All instead.
+func (s *Set[T]) Items() (c chan T) {
+	c = make(chan T)
+	go func() {
+		defer close(c)
+		for k := range s.set {
+			c <- k
+		}
+	}()
+	return c
+}
+
+// Len returns the length of the set
+func (s *Set[T]) Len() int {
+	return len(s.set)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (s *Set[T]) UnmarshalJSON(data []byte) (err error) {
+	tmp := make([]T, 0)
+	s.i = 0
+	s.set = make(map[T]uint)
+	if err = json.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+	s.Add(tmp...)
+	return
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (s *Set[T]) MarshalJSON() (data []byte, err error) {
+	return json.Marshal(s.SortSlice())
+}
+
+// SyncedSet datastruct that represent a thread safe set
+type SyncedSet[T comparable] struct {
+	sync.RWMutex
+	set *Set[T]
+}
+
+// NewSyncedSet constructs a new SyncedSet containing the data of sets
+func NewSyncedSet[T comparable](sets ...*SyncedSet[T]) *SyncedSet[T] {
+	ss := &SyncedSet[T]{set: NewSet[T]()}
+	for _, set := range sets {
+		ss.Add(set.Slice()...)
+	}
+	return ss
+}
+
+// NewInitSyncedSet constructs a new SyncedSet initialized with data
+func NewInitSyncedSet[T comparable](data ...T) *SyncedSet[T] {
+	ss := &SyncedSet[T]{set: NewSet[T]()}
+	ss.Add(data...)
+	return ss
+}
+
+// Equal returns true if both sets are equal
+func (s *SyncedSet[T]) Equal(other *SyncedSet[T]) bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.set.Equal(other.set)
+}
+
+// Add adds data to the set
+func (s *SyncedSet[T]) Add(data ...T) {
+	s.Lock()
+	defer s.Unlock()
+	s.set.Add(data...)
+}
+
+// Del deletes data from the set
+func (s *SyncedSet[T]) Del(data ...T) {
+	s.Lock()
+	defer s.Unlock()
+	s.set.Del(data...)
+}
+
+// Intersect returns a pointer to a new set containing the intersection of current
+// set and other
+func (s *SyncedSet[T]) Intersect(other *SyncedSet[T]) *SyncedSet[T] {
+	s.RLock()
+	defer s.RUnlock()
+	return NewInitSyncedSet(s.set.Intersect(other.set).Slice()...)
+}
+
+// Union returns a pointer to a new set containing the union of current set and other
+func (s *SyncedSet[T]) Union(other *SyncedSet[T]) *SyncedSet[T] {
+	s.RLock()
+	defer s.RUnlock()
+	return NewInitSyncedSet(s.set.Union(other.set).Slice()...)
+}
+
+// Contains returns true if the syncedset contains all the data
+func (s *SyncedSet[T]) Contains(data ...T) bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.set.Contains(data...)
+}
+
+// Slice returns a pointer to a new slice containing the data in the set
+func (s *SyncedSet[T]) Slice() []T {
+	s.RLock()
+	defer s.RUnlock()
+	return s.set.Slice()
+}
+
+// Range calls fn for every element of the set until fn returns false. The
+// set is read locked during the iteration so fn must not modify it.
+func (s *SyncedSet[T]) Range(fn func(T) bool) {
+	s.RLock()
+	defer s.RUnlock()
+	s.set.Range(fn)
+}
+
+// All returns an iterator over the elements of the set, which is read locked
+// during the iteration (c.f. Range)
+func (s *SyncedSet[T]) All() iter.Seq[T] {
+	return s.Range
+}
+
+// Items returns a channel with all the elements contained in the set
+//
+// Deprecated: the goroutine feeding the channel leaks if the channel is not
+// drained, use Range or All instead.
+func (s *SyncedSet[T]) Items() (c chan T) {
+	// the channel is fed from a copy as the lock is released meanwhile
+	return feed(s.Slice())
+}
+
+// Len returns the length of the syncedset
+func (s *SyncedSet[T]) Len() int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.set.Len()
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (s *SyncedSet[T]) UnmarshalJSON(data []byte) (err error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.set == nil {
+		s.set = NewSet[T]()
+	}
+	return s.set.UnmarshalJSON(data)
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (s *SyncedSet[T]) MarshalJSON() (data []byte, err error) {
+	s.RLock()
+	defer s.RUnlock()
+	return json.Marshal(&s.set)
+}
@@ -0,0 +1,94 @@
+package generic
+
+import (
+	"encoding/json"
+	"runtime"
+	"testing"
+)
+
+func TestSets(t *testing.T) {
+	s1 := NewInitSyncedSet("This", "is", "foo", "!!")
+	s2 := NewInitSyncedSet("This", "is", "bar", "!!!")
+
+	if !s1.Intersect(s2).Equal(NewInitSyncedSet("This", "is")) {
+		t.Error("Bad intersection")
+	}
+	union := s1.Union(s2)
+	if union.Len() != 6 || !union.Contains("foo", "bar", "!!", "!!!") {
+		t.Error("Bad union")
+	}
+	union.Del("foo", "bar")
+	if union.Contains("foo") || union.Contains("bar") {
+		t.Error("Values should be deleted")
+	}
+	if !s1.Equal(NewSyncedSet(s1)) {
+		t.Error("Copy must be equal")
+	}
+}
+
+func TestSetJSON(t *testing.T) {
+	s := NewSet[int]()
+	for i := 0; i < 1000; i++ {
+		s.Add(i)
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// values are unmarshalled with their type
+	ns := NewSet[int]()
+	if err := json.Unmarshal(data, &ns); err != nil {
+		t.Fatal(err)
+	}
+	if !ns.Equal(s) {
+		t.Error("Bad unmarshalled set")
+	}
+	for i, v := range ns.SortSlice() {
+		if v != i {
+			t.Fatalf("Bad set order: %d at %d", v, i)
+		}
+	}
+
+	var ss SyncedSet[string]
+	if err := json.Unmarshal([]byte(`["foo","bar"]`), &ss); err != nil {
+		t.Fatal(err)
+	}
+	if !ss.Contains("foo", "bar") {
+		t.Error("Set does not contain expected data")
+	}
+}
+
+func TestSetIteration(t *testing.T) {
+	s := NewInitSyncedSet(1, 2, 3, 4, 5)
+	goroutines := runtime.NumGoroutine()
+
+	n := 0
+	s.Range(func(int) bool {
+		n++
+		return n < 2
+	})
+	if n != 2 {
+		t.Errorf("Range must stop early: %d", n)
+	}
+
+	sum := 0
+	for i := range s.All() {
+		sum += i
+		if sum > 100 {
+			break
+		}
+	}
+	if sum != 15 {
+		t.Errorf("Bad sum: %d", sum)
+	}
+	for range s.All() {
+		break
+	}
+
+	// the read lock must be released when breaking out of the loop
+	s.Add(6)
+	if runtime.NumGoroutine() != goroutines {
+		t.Error("Iteration must not start goroutines")
+	}
+}
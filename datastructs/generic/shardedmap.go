@@ -0,0 +1,285 @@
+package generic
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/maphash"
+	"iter"
+	"math"
+	"math/bits"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+type shard[K comparable, V any] struct {
+	sync.RWMutex
+	m map[K]V
+	// pad shards to a cache line so that locking a shard does not slow down
+	// the access to its neighbours
+	_ [32]byte
+}
+
+// ShardedMap is a thread safe map split into shards locked independently,
+// which scales better than SyncedMap or SyncedHashMap with many goroutines.
+// Operations on a key are atomic, the ones on the whole map are not unless
+// stated otherwise.
+type ShardedMap[K comparable, V any] struct {
+	shards []shard[K, V]
+	hash   func(K) uint64
+	mask   uint64
+}
+
+// NewShardedMap returns an empty map of shards shards, rounded up to a power
+// of two, keys being dispatched to shards by hash. A shards value lower
+// than 1 uses four shards per CPU and a nil hash uses a seeded hash of the
+// keys (c.f. hashComparable).
+func NewShardedMap[K comparable, V any](shards int, hash func(K) uint64) *ShardedMap[K, V] {
+	if shards < 1 {
+		shards = 4 * runtime.GOMAXPROCS(0)
+	}
+	if hash == nil {
+		hash = hashComparable[K](maphash.MakeSeed())
+	}
+	n := 1 << bits.Len(uint(shards-1))
+	m := &ShardedMap[K, V]{shards: make([]shard[K, V], n), hash: hash, mask: uint64(n - 1)}
+	for i := range m.shards {
+		m.shards[i].m = make(map[K]V)
+	}
+	return m
+}
+
+// hashComparable returns a hash function of keys seeded by seed, keys equal
+// as per == having the same hash. Strings and integers are hashed directly,
+// other keys are walked with reflection.
+func hashComparable[K comparable](seed maphash.Seed) func(K) uint64 {
+	return func(k K) uint64 {
+		var b [8]byte
+		switch k := any(k).(type) {
+		case string:
+			return maphash.String(seed, k)
+		case int:
+			return maphash.Bytes(seed, binary.LittleEndian.AppendUint64(b[:0], uint64(k)))
+		case int64:
+			return maphash.Bytes(seed, binary.LittleEndian.AppendUint64(b[:0], uint64(k)))
+		case uint64:
+			return maphash.Bytes(seed, binary.LittleEndian.AppendUint64(b[:0], k))
+		}
+		var h maphash.Hash
+		h.SetSeed(seed)
+		writeComparable(&h, reflect.ValueOf(&k).Elem())
+		return h.Sum64()
+	}
+}
+
+// writeComparable writes the value of v to h so that equal values are
+// written the same way
+func writeComparable(h *maphash.Hash, v reflect.Value) {
+	var b [8]byte
+	writeUint := func(x uint64) {
+		h.Write(binary.LittleEndian.AppendUint64(b[:0], x))
+	}
+	writeFloat := func(f float64) {
+		// -0 equals 0
+		if f == 0 {
+			f = 0
+		}
+		writeUint(math.Float64bits(f))
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			h.WriteByte(1)
+		} else {
+			h.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		writeFloat(real(v.Complex()))
+		writeFloat(imag(v.Complex()))
+	case reflect.String:
+		h.WriteString(v.String())
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		writeUint(uint64(v.Pointer()))
+	case reflect.Interface:
+		// values of different types may have the same hash
+		if !v.IsNil() {
+			writeComparable(h, v.Elem())
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			writeComparable(h, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			// blank fields are ignored by ==
+			if v.Type().Field(i).Name != "_" {
+				writeComparable(h, v.Field(i))
+			}
+		}
+	default:
+		panic(fmt.Sprintf("hash of unhashable type %s", v.Type()))
+	}
+}
+
+func (m *ShardedMap[K, V]) shard(key K) *shard[K, V] {
+	return &m.shards[m.hash(key)&m.mask]
+}
+
+// Get returns the value of key
+func (m *ShardedMap[K, V]) Get(key K) (value V, ok bool) {
+	s := m.shard(key)
+	s.RLock()
+	defer s.RUnlock()
+	value, ok = s.m[key]
+	return
+}
+
+// Contains returns true if key is in the map
+func (m *ShardedMap[K, V]) Contains(key K) bool {
+	_, ok := m.Get(key)
+	return ok
+}
+
+// Add adds or updates key with value
+func (m *ShardedMap[K, V]) Add(key K, value V) {
+	s := m.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	s.m[key] = value
+}
+
+// Del deletes key and returns its value, ok being false if key was not in
+// the map
+func (m *ShardedMap[K, V]) Del(key K) (value V, ok bool) {
+	s := m.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	if value, ok = s.m[key]; ok {
+		delete(s.m, key)
+	}
+	return
+}
+
+// GetOrAdd returns the value of key if it is in the map, loaded being true,
+// and adds it with value otherwise
+func (m *ShardedMap[K, V]) GetOrAdd(key K, value V) (actual V, loaded bool) {
+	return m.GetOrAddFunc(key, func() V { return value })
+}
+
+// GetOrAddFunc is like GetOrAdd but the value added is created by fn, which
+// is only called if key is not in the map. The shard of key is locked while
+// fn runs so fn must not use the map.
+func (m *ShardedMap[K, V]) GetOrAddFunc(key K, fn func() V) (actual V, loaded bool) {
+	s := m.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	if actual, loaded = s.m[key]; !loaded {
+		actual = fn()
+		s.m[key] = actual
+	}
+	return
+}
+
+// Compute atomically sets the value of key to the one returned by fn,
+// called with the current value and whether key is in the map. The key is
+// deleted if fn returns keep false. It returns the new value and whether key
+// is in the map afterwards. The shard of key is locked while fn runs so fn
+// must not use the map.
+func (m *ShardedMap[K, V]) Compute(key K, fn func(old V, ok bool) (new V, keep bool)) (V, bool) {
+	s := m.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	old, ok := s.m[key]
+	new, keep := fn(old, ok)
+	if !keep {
+		delete(s.m, key)
+		var zero V
+		return zero, false
+	}
+	s.m[key] = new
+	return new, true
+}
+
+// Update atomically sets the value of key to the one returned by fn, called
+// with the current value, if key is in the map. It returns the new value,
+// ok being false if key is not in the map (c.f. Compute).
+func (m *ShardedMap[K, V]) Update(key K, fn func(V) V) (value V, ok bool) {
+	s := m.shard(key)
+	s.Lock()
+	defer s.Unlock()
+	if value, ok = s.m[key]; ok {
+		value = fn(value)
+		s.m[key] = value
+	}
+	return
+}
+
+// Range calls fn with the items of the map until fn returns false. Shards
+// are read locked one after the other so fn must not modify the map, and
+// the items do not form a consistent state of the map if it is modified
+// concurrently (c.f. Snapshot).
+func (m *ShardedMap[K, V]) Range(fn func(K, V) bool) {
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.RLock()
+		for k, v := range s.m {
+			if !fn(k, v) {
+				s.RUnlock()
+				return
+			}
+		}
+		s.RUnlock()
+	}
+}
+
+// All returns an iterator over the items of the map (c.f. Range)
+func (m *ShardedMap[K, V]) All() iter.Seq2[K, V] {
+	return m.Range
+}
+
+// Snapshot returns a copy of the map taken with all the shards locked, which
+// is a consistent state of the map
+func (m *ShardedMap[K, V]) Snapshot() map[K]V {
+	for i := range m.shards {
+		m.shards[i].RLock()
+	}
+	defer func() {
+		for i := range m.shards {
+			m.shards[i].RUnlock()
+		}
+	}()
+	n := 0
+	for i := range m.shards {
+		n += len(m.shards[i].m)
+	}
+	snap := make(map[K]V, n)
+	for i := range m.shards {
+		for k, v := range m.shards[i].m {
+			snap[k] = v
+		}
+	}
+	return snap
+}
+
+// Len returns the number of items in the map
+func (m *ShardedMap[K, V]) Len() (n int) {
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.RLock()
+		n += len(s.m)
+		s.RUnlock()
+	}
+	return
+}
+
+// Shards returns the number of shards of the map
+func (m *ShardedMap[K, V]) Shards() int {
+	return len(m.shards)
+}
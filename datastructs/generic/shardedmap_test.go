@@ -0,0 +1,113 @@
+package generic
+
+import (
+	"hash/maphash"
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestShardedMap(t *testing.T) {
+	m := NewShardedMap[int, int](5, nil)
+	if m.Shards() != 8 {
+		t.Errorf("Bad number of shards: %d", m.Shards())
+	}
+	for i := 0; i < 1000; i++ {
+		m.Add(i, i)
+	}
+	if v, ok := m.Get(42); !ok || v != 42 || m.Len() != 1000 {
+		t.Error("Bad map content")
+	}
+	if v, ok := m.Del(42); !ok || v != 42 || m.Contains(42) {
+		t.Error("Bad deletion")
+	}
+	if _, ok := m.Del(42); ok {
+		t.Error("Key must be deleted")
+	}
+
+	if v, loaded := m.GetOrAdd(1, 100); !loaded || v != 1 {
+		t.Error("Bad GetOrAdd of existing key")
+	}
+	if v, loaded := m.GetOrAdd(42, 100); loaded || v != 100 {
+		t.Error("Bad GetOrAdd of missing key")
+	}
+	if v, ok := m.Update(42, func(v int) int { return v + 1 }); !ok || v != 101 {
+		t.Error("Bad update")
+	}
+	if _, ok := m.Update(-1, func(v int) int { return v + 1 }); ok || m.Contains(-1) {
+		t.Error("Update must not add keys")
+	}
+	if _, ok := m.Compute(42, func(old int, ok bool) (int, bool) { return 0, false }); ok || m.Contains(42) {
+		t.Error("Compute must delete the key")
+	}
+
+	snap := m.Snapshot()
+	n := 0
+	for k, v := range m.All() {
+		if snap[k] != v {
+			t.Errorf("Bad snapshot value for %d", k)
+		}
+		n++
+	}
+	if n != len(snap) || n != m.Len() {
+		t.Errorf("Bad number of items: %d", n)
+	}
+}
+
+func TestHashComparable(t *testing.T) {
+	type key struct {
+		S string
+		F float64
+		_ int
+		P *int
+		I interface{}
+		A [2]uint8
+	}
+	x := 1
+	hash := hashComparable[key](maphash.MakeSeed())
+	a := key{S: "a", F: 0, P: &x, I: 1, A: [2]uint8{1, 2}}
+	b := a
+	b.F = math.Copysign(0, -1)
+	if a != b || hash(a) != hash(b) {
+		t.Error("Equal keys must have the same hash")
+	}
+	b.I = 2
+	if hash(a) == hash(b) {
+		t.Error("Different keys should have different hashes")
+	}
+
+	m := NewShardedMap[interface{}, int](0, nil)
+	for _, k := range []interface{}{"a", 1, int64(1), 1.5, a, [2]string{"a", "b"}} {
+		m.Add(k, 1)
+		if !m.Contains(k) {
+			t.Errorf("Missing key %v", k)
+		}
+	}
+	if m.Len() != 6 {
+		t.Errorf("Bad length: %d", m.Len())
+	}
+}
+
+func TestShardedMapConcurrency(t *testing.T) {
+	// counters updated by several workers must not lose increments
+	m := NewShardedMap[string, int](0, func(k string) uint64 { return uint64(len(k)) })
+	keys := []string{"a", "bb", "ccc", "dddd"}
+	workers, n := 8, 1000
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				m.Compute(keys[j%len(keys)], func(old int, ok bool) (int, bool) { return old + 1, true })
+				m.Snapshot()
+			}
+		}()
+	}
+	wg.Wait()
+	for _, k := range keys {
+		if v, _ := m.Get(k); v != workers*n/len(keys) {
+			t.Errorf("Bad counter %s: %d", k, v)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package generic
+
+import (
+	"fmt"
+	"iter"
+)
+
+// SortedSlice structure
+// by convention the smallest value is at the end
+type SortedSlice[T any] struct {
+	s    []T
+	less func(a, b T) bool
+}
+
+// NewSortedSlice returns an empty initialized slice ordered by less, which
+// returns true if a is less than b. Opts takes len and cap in order to
+// initialize the underlying slice
+func NewSortedSlice[T any](less func(a, b T) bool, opts ...int) *SortedSlice[T] {
+	l, c := 0, 0
+	if len(opts) >= 1 {
+		l = opts[0]
+	}
+	if len(opts) >= 2 {
+		c = opts[1]
+	}
+	return &SortedSlice[T]{make([]T, l, c), less}
+}
+
+// Recursive function to search for the next index less than e
+func (ss *SortedSlice[T]) searchLessThan(e T, i, j int) int {
+	pivot := ((j + 1 - i) / 2) + i
+	if j-i == 1 {
+		if ss.less(ss.s[i], e) {
+			return i
+		}
+		return j
+	}
+	if ss.less(ss.s[pivot], e) {
+		return ss.searchLessThan(e, i, pivot)
+	}
+	return ss.searchLessThan(e, pivot, j)
+}
+
+// RangeLessThan returns the indexes of the objects less than e
+func (ss *SortedSlice[T]) RangeLessThan(e T) (int, int) {
+	i := ss.searchLessThan(e, 0, len(ss.s)-1)
+	return i, len(ss.s) - 1
+}
+
+// Insert inserts e in the slice
+func (ss *SortedSlice[T]) Insert(e T) {
+	switch {
+	// Particular cases
+	case len(ss.s) == 0, !ss.less(ss.s[len(ss.s)-1], e):
+		ss.s = append(ss.s, e)
+	case len(ss.s) == 1 && ss.less(ss.s[0], e):
+		ss.s = append(ss.s, e)
+		ss.s[1] = ss.s[0]
+		ss.s[0] = e
+	default:
+		i := ss.searchLessThan(e, 0, len(ss.s)-1)
+		// Avoid creating intermediary slices
+		ss.s = append(ss.s, e)
+		copy(ss.s[i+1:], ss.s[i:])
+		ss.s[i] = e
+	}
+}
+
+// bounds returns the start and stop indexes given to iterators
+func (ss *SortedSlice[T]) bounds(idx []int) (i, j int, ok bool) {
+	i, j = 0, len(ss.s)-1
+	if len(idx) >= 1 {
+		i = idx[0]
+	}
+	if len(idx) >= 2 {
+		j = idx[1]
+	}
+	return i, j, i < len(ss.s) && j < len(ss.s) && i <= j && i >= 0
+}
+
+// Range calls fn for the items in the slice until fn returns false. Start
+// and Stop indexes can be specified via optional parameters
+func (ss *SortedSlice[T]) Range(fn func(T) bool, idx ...int) {
+	i, j, ok := ss.bounds(idx)
+	for ; ok && i <= j; i++ {
+		if !fn(ss.s[i]) {
+			return
+		}
+	}
+}
+
+// ReversedRange calls fn for the items in the slice, in reverse order, until
+// fn returns false. Start and Stop indexes can be specified via optional
+// parameters
+func (ss *SortedSlice[T]) ReversedRange(fn func(T) bool, idx ...int) {
+	i, j, ok := ss.bounds(idx)
+	for k := len(ss.s) - 1 - i; ok && k >= len(ss.s)-1-j; k-- {
+		if !fn(ss.s[k]) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over the items in the slice. Start and Stop
+// indexes can be specified via optional parameters
+func (ss *SortedSlice[T]) All(idx ...int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		ss.Range(yield, idx...)
+	}
+}
+
+// Backward returns an iterator over the items in the slice in reverse order.
+// Start and Stop indexes can be specified via optional parameters
+func (ss *SortedSlice[T]) Backward(idx ...int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		ss.ReversedRange(yield, idx...)
+	}
+}
+
+// Iter returns a chan of the items in the slice. Start and Stop indexes can
+// be specified via optional parameters
+//
+// Deprecated: the goroutine feeding the channel leaks if the channel is not
+// drained, use Range or All instead.
+func (ss *SortedSlice[T]) Iter(idx ...int) (c chan T) {
+	c = make(chan T)
+	i, j, ok := ss.bounds(idx)
+	if !ok {
+		close(c)
+		return c
+	}
+	go func() {
+		defer close(c)
+		for ; i <= j; i++ {
+			c <- ss.s[i]
+		}
+	}()
+	return c
+}
+
+// ReversedIter returns a chan of the items in the slice but in reverse
+// order. Start and Stop indexes can be specified via optional parameters
+//
+// Deprecated: the goroutine feeding the channel leaks if the channel is not
+// drained, use ReversedRange or Backward instead.
+func (ss *SortedSlice[T]) ReversedIter(idx ...int) (c chan T) {
+	c = make(chan T)
+	i, j, ok := ss.bounds(idx)
+	if !ok {
+		close(c)
+		return c
+	}
+	go func() {
+		defer close(c)
+		for k := len(ss.s) - 1 - i; k >= len(ss.s)-1-j; k-- {
+			c <- ss.s[k]
+		}
+	}()
+	return c
+}
+
+// Slice returns the underlying slice
+func (ss *SortedSlice[T]) Slice() []T {
+	return ss.s
+}
+
+// Control controls if the slice has been properly ordered. A return value of
+// true means it is in good order
+func (ss *SortedSlice[T]) Control() bool {
+	for i := 1; i < len(ss.s); i++ {
+		if ss.less(ss.s[i-1], ss.s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// String fmt helper
+func (ss *SortedSlice[T]) String() string {
+	return fmt.Sprintf("%v", ss.s)
+}
@@ -0,0 +1,72 @@
+package generic
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestSortedSlice(t *testing.T) {
+	s := NewSortedSlice(func(a, b int) bool { return a < b })
+	for i := 0; i < 1000; i++ {
+		s.Insert(rand.Intn(100))
+		if !s.Control() {
+			t.Fatalf("Bad order: %s", s)
+		}
+	}
+
+	prev := -1
+	for i := range s.ReversedIter() {
+		if i < prev {
+			t.Fatalf("Bad reversed order: %s", s)
+		}
+		prev = i
+	}
+	if i, j := s.RangeLessThan(50); s.Slice()[i] >= 50 || j != len(s.Slice())-1 {
+		t.Errorf("Bad range: %d, %d", i, j)
+	}
+}
+
+func TestSortedSliceTime(t *testing.T) {
+	now := time.Now()
+	s := NewSortedSlice(func(a, b time.Time) bool { return a.Before(b) })
+	for i := 0; i < 50; i++ {
+		s.Insert(now.Add(time.Second * time.Duration(rand.Int63()%3600)))
+	}
+	if !s.Control() {
+		t.Errorf("Bad order: %s", s)
+	}
+	n := 0
+	for range s.Iter(0, 9) {
+		n++
+	}
+	if n != 10 {
+		t.Errorf("Bad number of items iterated: %d", n)
+	}
+}
+
+func TestSortedSliceIteration(t *testing.T) {
+	s := NewSortedSlice(func(a, b int) bool { return a < b })
+	for i := 0; i < 10; i++ {
+		s.Insert(i)
+	}
+
+	var got []int
+	for i := range s.All(2, 5) {
+		got = append(got, i)
+	}
+	for i := range s.Backward() {
+		if got = append(got, i); i == 2 {
+			break
+		}
+	}
+	s.ReversedRange(func(i int) bool {
+		got = append(got, i)
+		return false
+	}, 9)
+	expected := []int{7, 6, 5, 4, 0, 1, 2, 9}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Bad iteration: %v", got)
+	}
+}
@@ -0,0 +1,27 @@
+package datastructs
+
+import "github.com/0xrawsec/golang-utils/datastructs/generic"
+
+// Heap is a binary heap of Sortable elements, the smallest element being
+// popped first (c.f. generic.Heap)
+type Heap = generic.Heap[Sortable]
+
+// NewHeap returns an empty Heap
+func NewHeap() *Heap {
+	return generic.NewHeap(lessSortable)
+}
+
+// SyncedHeap is a thread safe Heap whose Pop waits for an element to be
+// pushed (c.f. generic.SyncedHeap)
+type SyncedHeap = generic.SyncedHeap[Sortable]
+
+// NewSyncedHeap returns an empty SyncedHeap
+func NewSyncedHeap() *SyncedHeap {
+	return generic.NewSyncedHeap(lessSortable)
+}
+
+// NewIndexedHeap returns an empty heap of Sortable values identified by a
+// key (c.f. generic.IndexedHeap)
+func NewIndexedHeap[K comparable]() *generic.IndexedHeap[K, Sortable] {
+	return generic.NewIndexedHeap[K](lessSortable)
+}
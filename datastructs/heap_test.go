@@ -0,0 +1,51 @@
+package datastructs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHeap(t *testing.T) {
+	h := NewHeap()
+	for _, i := range ints {
+		h.Push(MyInt(i))
+	}
+	prev := MyInt(-1 << 31)
+	for h.Len() > 0 {
+		e, _ := h.Pop()
+		if e.Less(prev) {
+			t.Fatalf("Bad order: %v after %v", e, prev)
+		}
+		prev = e.(MyInt)
+	}
+}
+
+func TestSyncedHeap(t *testing.T) {
+	h := NewSyncedHeap()
+	now := time.Now()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		h.Push(MyTime{now.Add(time.Second)})
+		h.Push(MyTime{now})
+	}()
+	e, err := h.Pop(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := h.TryPop(); e == nil {
+		t.Error("Missing element")
+	}
+	t.Log(e)
+}
+
+func TestIndexedHeap(t *testing.T) {
+	h := NewIndexedHeap[string]()
+	h.Push("a", MyInt(3))
+	h.Push("b", MyInt(2))
+	h.Push("c", MyInt(1))
+	h.Update("c", MyInt(4))
+	if k, v, _ := h.Pop(); k != "b" || v != MyInt(2) {
+		t.Errorf("Bad pop: %s=%v", k, v)
+	}
+}
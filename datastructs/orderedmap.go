@@ -0,0 +1,12 @@
+package datastructs
+
+import "github.com/0xrawsec/golang-utils/datastructs/generic"
+
+// OrderedMap is a map whose Sortable keys are kept ordered, supporting range
+// queries (c.f. generic.OrderedMap)
+type OrderedMap = generic.OrderedMap[Sortable, interface{}]
+
+// NewOrderedMap returns an empty OrderedMap
+func NewOrderedMap() *OrderedMap {
+	return generic.NewOrderedMap[Sortable, interface{}](compareSortable)
+}
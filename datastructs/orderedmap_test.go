@@ -0,0 +1,20 @@
+package datastructs
+
+import "testing"
+
+func TestOrderedMap(t *testing.T) {
+	m := NewOrderedMap()
+	for _, i := range ints {
+		m.Add(MyInt(i), i)
+	}
+	if k, _, _ := m.Floor(MyInt(50)); k != MyInt(13) {
+		t.Errorf("Bad floor: %v", k)
+	}
+	prev := MyInt(-1 << 31)
+	for k := range m.All() {
+		if !prev.Less(k) {
+			t.Errorf("Bad order: %v after %v", k, prev)
+		}
+		prev = k.(MyInt)
+	}
+}
@@ -104,11 +104,11 @@ func TestRingSet(t *testing.T) {
 		}
 	}
 
-	if r.rslice.Len() != r.set.Len() {
+	if r.RingSlice().Len() != r.Set().Len() {
 		t.Errorf("RingSlice and Set must have the same size")
 	}
 
-	if r.set.Len() != r.Len() {
+	if r.Set().Len() != r.Len() {
 		t.Errorf("Inconsistent size")
 	}
 
@@ -140,7 +140,7 @@ func TestRingSet(t *testing.T) {
 		}
 	}
 
-	if new.rslice.Len() != new.set.Len() {
+	if new.RingSlice().Len() != new.Set().Len() {
 		t.Errorf("RingSlice and Set must have the same size, even after json un/marshalling")
 	}
 }
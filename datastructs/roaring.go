@@ -0,0 +1,782 @@
+package datastructs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"iter"
+	"math/bits"
+	"slices"
+	"sort"
+)
+
+const (
+	// arrayMaxSize is the maximum cardinality of array containers
+	arrayMaxSize = 4096
+	// bitmapWords is the number of words of bitmap containers
+	bitmapWords = 1 << 16 / wordSize
+	// bitmapBytes is the serialized size of bitmap containers
+	bitmapBytes = bitmapWords * 8
+	// maxRuns is the number of runs above which run containers are larger
+	// than bitmap containers
+	maxRuns = (bitmapBytes - 2) / 4
+
+	// cookies of the portable serialization format
+	serialCookieNoRun = 12346
+	serialCookie      = 12347
+	// number of containers from which offsets are serialized when there are
+	// run containers
+	noOffsetThreshold = 4
+)
+
+var (
+	// ErrInvalidBitmap is returned when unmarshalling invalid RoaringBitmap
+	// data
+	ErrInvalidBitmap = errors.New("Invalid bitmap")
+)
+
+// container holds the 16 low bits of the values of a RoaringBitmap sharing
+// the same 16 high bits. Methods modifying a container return the container
+// to use afterwards, which may be of another kind.
+type container interface {
+	add(x uint16) container
+	remove(x uint16) container
+	contains(x uint16) bool
+	cardinality() int
+	numRuns() int
+	// iterate calls fn with the values in ascending order until fn returns
+	// false, returning false if fn did
+	iterate(fn func(uint16) bool) bool
+	// toBitmap returns the container itself if it is a bitmap container or
+	// a new bitmap container
+	toBitmap() *bitmapContainer
+	clone() container
+	appendTo(b []byte) []byte
+}
+
+// arrayContainer is a sorted array of values used for sparse containers
+type arrayContainer []uint16
+
+func (a arrayContainer) add(x uint16) container {
+	i, found := slices.BinarySearch(a, x)
+	switch {
+	case found:
+		return a
+	case len(a) >= arrayMaxSize:
+		return a.toBitmap().add(x)
+	}
+	return slices.Insert(a, i, x)
+}
+
+func (a arrayContainer) remove(x uint16) container {
+	if i, found := slices.BinarySearch(a, x); found {
+		return slices.Delete(a, i, i+1)
+	}
+	return a
+}
+
+func (a arrayContainer) contains(x uint16) bool {
+	_, found := slices.BinarySearch(a, x)
+	return found
+}
+
+func (a arrayContainer) cardinality() int {
+	return len(a)
+}
+
+func (a arrayContainer) numRuns() (n int) {
+	for i := range a {
+		if i == 0 || a[i] != a[i-1]+1 {
+			n++
+		}
+	}
+	return
+}
+
+func (a arrayContainer) iterate(fn func(uint16) bool) bool {
+	for _, x := range a {
+		if !fn(x) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a arrayContainer) toBitmap() *bitmapContainer {
+	b := &bitmapContainer{card: len(a)}
+	for _, x := range a {
+		b.words[x/wordSize] |= 1 << (x % wordSize)
+	}
+	return b
+}
+
+func (a arrayContainer) clone() container {
+	return slices.Clone(a)
+}
+
+func (a arrayContainer) appendTo(b []byte) []byte {
+	for _, x := range a {
+		b = binary.LittleEndian.AppendUint16(b, x)
+	}
+	return b
+}
+
+// filter returns a new array container with the values for which keep
+// returns true
+func (a arrayContainer) filter(keep func(uint16) bool) arrayContainer {
+	out := make(arrayContainer, 0, len(a))
+	for _, x := range a {
+		if keep(x) {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+// bitmapContainer is a bitmap of the 2^16 values used for dense containers
+type bitmapContainer struct {
+	words [bitmapWords]uint64
+	card  int
+}
+
+func (b *bitmapContainer) add(x uint16) container {
+	if w, mask := &b.words[x/wordSize], uint64(1)<<(x%wordSize); *w&mask == 0 {
+		*w |= mask
+		b.card++
+	}
+	return b
+}
+
+func (b *bitmapContainer) remove(x uint16) container {
+	if w, mask := &b.words[x/wordSize], uint64(1)<<(x%wordSize); *w&mask != 0 {
+		*w &^= mask
+		if b.card--; b.card <= arrayMaxSize {
+			return toArray(b)
+		}
+	}
+	return b
+}
+
+func (b *bitmapContainer) contains(x uint16) bool {
+	return b.words[x/wordSize]&(1<<(x%wordSize)) != 0
+}
+
+func (b *bitmapContainer) cardinality() int {
+	return b.card
+}
+
+func (b *bitmapContainer) numRuns() (n int) {
+	var prev uint64
+	for _, w := range b.words {
+		// bits starting a run are set bits whose previous bit is cleared
+		n += bits.OnesCount64(w &^ (w<<1 | prev>>(wordSize-1)))
+		prev = w
+	}
+	return
+}
+
+func (b *bitmapContainer) iterate(fn func(uint16) bool) bool {
+	for i, w := range b.words {
+		for w != 0 {
+			if !fn(uint16(i*wordSize + bits.TrailingZeros64(w))) {
+				return false
+			}
+			w &= w - 1
+		}
+	}
+	return true
+}
+
+func (b *bitmapContainer) toBitmap() *bitmapContainer {
+	return b
+}
+
+func (b *bitmapContainer) clone() container {
+	new := *b
+	return &new
+}
+
+func (b *bitmapContainer) appendTo(out []byte) []byte {
+	for _, w := range b.words {
+		out = binary.LittleEndian.AppendUint64(out, w)
+	}
+	return out
+}
+
+// addRange sets the values from start to last
+func (b *bitmapContainer) addRange(start, last int) {
+	for i := start / wordSize; i <= last/wordSize; i++ {
+		lo, hi := max(start, i*wordSize)-i*wordSize, min(last, i*wordSize+wordSize-1)-i*wordSize
+		b.words[i] |= (^uint64(0) >> uint(wordSize-1-(hi-lo))) << uint(lo)
+	}
+	b.count()
+}
+
+// count updates the cardinality of the container
+func (b *bitmapContainer) count() {
+	b.card = 0
+	for _, w := range b.words {
+		b.card += bits.OnesCount64(w)
+	}
+}
+
+// interval16 is a run of values from start to last
+type interval16 struct {
+	start, last uint16
+}
+
+// runContainer is a sorted list of runs of values used for containers made
+// of consecutive values
+type runContainer []interval16
+
+func (r runContainer) add(x uint16) container {
+	xi := int(x)
+	// first run ending at x-1 or after
+	i := sort.Search(len(r), func(i int) bool { return int(r[i].last)+1 >= xi })
+	switch {
+	case i < len(r) && r[i].start <= x && r[i].last >= x:
+		return r
+	case i < len(r) && int(r[i].last)+1 == xi:
+		r[i].last = x
+		if i+1 < len(r) && int(r[i+1].start) == xi+1 {
+			r[i].last = r[i+1].last
+			r = slices.Delete(r, i+1, i+2)
+		}
+	case i < len(r) && int(r[i].start) == xi+1:
+		r[i].start = x
+	default:
+		if r = slices.Insert(r, i, interval16{x, x}); len(r) > maxRuns {
+			return optimize(r)
+		}
+	}
+	return r
+}
+
+func (r runContainer) remove(x uint16) container {
+	i := sort.Search(len(r), func(i int) bool { return r[i].last >= x })
+	if i == len(r) || r[i].start > x {
+		return r
+	}
+	switch {
+	case r[i].start == r[i].last:
+		r = slices.Delete(r, i, i+1)
+	case r[i].start == x:
+		r[i].start++
+	case r[i].last == x:
+		r[i].last--
+	default:
+		r = slices.Insert(r, i+1, interval16{x + 1, r[i].last})
+		r[i].last = x - 1
+		if len(r) > maxRuns {
+			return optimize(r)
+		}
+	}
+	return r
+}
+
+func (r runContainer) contains(x uint16) bool {
+	i := sort.Search(len(r), func(i int) bool { return r[i].last >= x })
+	return i < len(r) && r[i].start <= x
+}
+
+func (r runContainer) cardinality() (n int) {
+	for _, run := range r {
+		n += int(run.last-run.start) + 1
+	}
+	return
+}
+
+func (r runContainer) numRuns() int {
+	return len(r)
+}
+
+func (r runContainer) iterate(fn func(uint16) bool) bool {
+	for _, run := range r {
+		for x := int(run.start); x <= int(run.last); x++ {
+			if !fn(uint16(x)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (r runContainer) toBitmap() *bitmapContainer {
+	b := &bitmapContainer{}
+	for _, run := range r {
+		b.addRange(int(run.start), int(run.last))
+	}
+	return b
+}
+
+func (r runContainer) clone() container {
+	return slices.Clone(r)
+}
+
+func (r runContainer) appendTo(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(r)))
+	for _, run := range r {
+		b = binary.LittleEndian.AppendUint16(b, run.start)
+		b = binary.LittleEndian.AppendUint16(b, run.last-run.start)
+	}
+	return b
+}
+
+// toArray returns c as an array container
+func toArray(c container) arrayContainer {
+	if a, ok := c.(arrayContainer); ok {
+		return a
+	}
+	a := make(arrayContainer, 0, c.cardinality())
+	c.iterate(func(x uint16) bool {
+		a = append(a, x)
+		return true
+	})
+	return a
+}
+
+// toRuns returns c as a run container
+func toRuns(c container) runContainer {
+	if r, ok := c.(runContainer); ok {
+		return r
+	}
+	r := make(runContainer, 0, c.numRuns())
+	c.iterate(func(x uint16) bool {
+		if n := len(r); n > 0 && int(r[n-1].last)+1 == int(x) {
+			r[n-1].last = x
+		} else {
+			r = append(r, interval16{x, x})
+		}
+		return true
+	})
+	return r
+}
+
+// serializedSize returns the number of bytes c is serialized into
+func serializedSize(c container) int {
+	switch c := c.(type) {
+	case arrayContainer:
+		return 2 * len(c)
+	case runContainer:
+		return 2 + 4*len(c)
+	}
+	return bitmapBytes
+}
+
+// optimize returns c in its most compact kind, array containers being used
+// up to arrayMaxSize values and bitmap containers above unless run
+// containers are smaller
+func optimize(c container) container {
+	card := c.cardinality()
+	size := bitmapBytes
+	if card <= arrayMaxSize {
+		size = 2 * card
+	}
+	switch {
+	case 2+4*c.numRuns() < size:
+		return toRuns(c)
+	case card <= arrayMaxSize:
+		return toArray(c)
+	}
+	return c.toBitmap()
+}
+
+// setOp is a set operation on containers, defined by the values it keeps
+// and its operation on bitmap words
+type setOp struct {
+	onlyA, both, onlyB bool
+	word               func(x, y uint64) uint64
+}
+
+var (
+	opAnd    = setOp{false, true, false, func(x, y uint64) uint64 { return x & y }}
+	opOr     = setOp{true, true, true, func(x, y uint64) uint64 { return x | y }}
+	opXor    = setOp{true, false, true, func(x, y uint64) uint64 { return x ^ y }}
+	opAndNot = setOp{true, false, false, func(x, y uint64) uint64 { return x &^ y }}
+)
+
+// apply returns the result of op on a and b, a being possibly modified and
+// b left untouched. The result may be empty.
+func (op setOp) apply(a, b container) container {
+	aa, aok := a.(arrayContainer)
+	ba, bok := b.(arrayContainer)
+	switch {
+	case aok && bok:
+		return optimize(op.merge(aa, ba))
+	case aok && !op.onlyB:
+		// the result is a subset of a
+		return optimize(aa.filter(func(x uint16) bool { return b.contains(x) == op.both }))
+	case bok && !op.onlyA:
+		return optimize(ba.filter(a.contains))
+	}
+	x, y := a.toBitmap(), b.toBitmap()
+	for i := range x.words {
+		x.words[i] = op.word(x.words[i], y.words[i])
+	}
+	x.count()
+	return optimize(x)
+}
+
+// merge applies op to two array containers
+func (op setOp) merge(a, b arrayContainer) arrayContainer {
+	out := make(arrayContainer, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || i < len(a) && a[i] < b[j]:
+			if op.onlyA {
+				out = append(out, a[i])
+			}
+			i++
+		case i == len(a) || b[j] < a[i]:
+			if op.onlyB {
+				out = append(out, b[j])
+			}
+			j++
+		default:
+			if op.both {
+				out = append(out, a[i])
+			}
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+// RoaringBitmap is a compressed set of uint32 values. Values are grouped by
+// their 16 high bits into containers holding their 16 low bits either as a
+// sorted array, a bitmap or a list of runs, whichever is the most compact.
+// Unlike a BitSet, its size only depends on the values it holds.
+type RoaringBitmap struct {
+	// sorted high bits of the values and their containers
+	keys       []uint16
+	containers []container
+}
+
+// NewRoaringBitmap creates a new RoaringBitmap holding values
+func NewRoaringBitmap(values ...uint32) *RoaringBitmap {
+	r := &RoaringBitmap{}
+	for _, x := range values {
+		r.Add(x)
+	}
+	return r
+}
+
+// container returns the index of the container of key, creating an empty
+// one if needed
+func (r *RoaringBitmap) container(key uint16) int {
+	i, found := slices.BinarySearch(r.keys, key)
+	if !found {
+		r.keys = slices.Insert(r.keys, i, key)
+		r.containers = slices.Insert(r.containers, i, container(arrayContainer{}))
+	}
+	return i
+}
+
+// del deletes the container at index i
+func (r *RoaringBitmap) del(i int) {
+	r.keys = slices.Delete(r.keys, i, i+1)
+	r.containers = slices.Delete(r.containers, i, i+1)
+}
+
+// Add adds x to the bitmap
+func (r *RoaringBitmap) Add(x uint32) {
+	i := r.container(uint16(x >> 16))
+	r.containers[i] = r.containers[i].add(uint16(x))
+}
+
+// AddRange adds the values from start to last included
+func (r *RoaringBitmap) AddRange(start, last uint32) {
+	for key := start >> 16; key <= last>>16 && start <= last; key++ {
+		lo, hi := 0, 1<<16-1
+		if key == start>>16 {
+			lo = int(start & 0xffff)
+		}
+		if key == last>>16 {
+			hi = int(last & 0xffff)
+		}
+		i := r.container(uint16(key))
+		b := r.containers[i].toBitmap()
+		b.addRange(lo, hi)
+		r.containers[i] = optimize(b)
+	}
+}
+
+// Remove removes x from the bitmap
+func (r *RoaringBitmap) Remove(x uint32) {
+	if i, found := slices.BinarySearch(r.keys, uint16(x>>16)); found {
+		if r.containers[i] = r.containers[i].remove(uint16(x)); r.containers[i].cardinality() == 0 {
+			r.del(i)
+		}
+	}
+}
+
+// Contains returns true if the bitmap contains x
+func (r *RoaringBitmap) Contains(x uint32) bool {
+	i, found := slices.BinarySearch(r.keys, uint16(x>>16))
+	return found && r.containers[i].contains(uint16(x))
+}
+
+// Cardinality returns the number of values in the bitmap
+func (r *RoaringBitmap) Cardinality() (n uint64) {
+	for _, c := range r.containers {
+		n += uint64(c.cardinality())
+	}
+	return
+}
+
+// IsEmpty returns true if the bitmap holds no value
+func (r *RoaringBitmap) IsEmpty() bool {
+	return len(r.containers) == 0
+}
+
+// Range calls fn with the values of the bitmap in ascending order until fn
+// returns false
+func (r *RoaringBitmap) Range(fn func(uint32) bool) {
+	for i, c := range r.containers {
+		high := uint32(r.keys[i]) << 16
+		if !c.iterate(func(x uint16) bool { return fn(high | uint32(x)) }) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over the values of the bitmap in ascending order
+func (r *RoaringBitmap) All() iter.Seq[uint32] {
+	return r.Range
+}
+
+// combine applies op to r and other, storing the result in r
+func (r *RoaringBitmap) combine(other *RoaringBitmap, op setOp) *RoaringBitmap {
+	keys := make([]uint16, 0, len(r.keys)+len(other.keys))
+	containers := make([]container, 0, len(r.keys)+len(other.keys))
+	appendContainer := func(key uint16, c container) {
+		if c.cardinality() > 0 {
+			keys = append(keys, key)
+			containers = append(containers, c)
+		}
+	}
+	i, j := 0, 0
+	for i < len(r.keys) || j < len(other.keys) {
+		switch {
+		case j == len(other.keys) || i < len(r.keys) && r.keys[i] < other.keys[j]:
+			if op.onlyA {
+				appendContainer(r.keys[i], r.containers[i])
+			}
+			i++
+		case i == len(r.keys) || other.keys[j] < r.keys[i]:
+			if op.onlyB {
+				appendContainer(other.keys[j], other.containers[j].clone())
+			}
+			j++
+		default:
+			appendContainer(r.keys[i], op.apply(r.containers[i], other.containers[j]))
+			i++
+			j++
+		}
+	}
+	r.keys, r.containers = keys, containers
+	return r
+}
+
+// And sets r to the intersection of r and other and returns r
+func (r *RoaringBitmap) And(other *RoaringBitmap) *RoaringBitmap {
+	return r.combine(other, opAnd)
+}
+
+// Or sets r to the union of r and other and returns r
+func (r *RoaringBitmap) Or(other *RoaringBitmap) *RoaringBitmap {
+	return r.combine(other, opOr)
+}
+
+// Xor sets r to the symmetric difference of r and other and returns r
+func (r *RoaringBitmap) Xor(other *RoaringBitmap) *RoaringBitmap {
+	return r.combine(other, opXor)
+}
+
+// AndNot removes the values of other from r and returns r
+func (r *RoaringBitmap) AndNot(other *RoaringBitmap) *RoaringBitmap {
+	return r.combine(other, opAndNot)
+}
+
+// Equal returns true if both bitmaps hold the same values
+func (r *RoaringBitmap) Equal(other *RoaringBitmap) bool {
+	if !slices.Equal(r.keys, other.keys) {
+		return false
+	}
+	for i, c := range r.containers {
+		o := other.containers[i]
+		if c.cardinality() != o.cardinality() || c.toBitmap().words != o.toBitmap().words {
+			return false
+		}
+	}
+	return true
+}
+
+// Copy returns a copy of the bitmap
+func (r *RoaringBitmap) Copy() *RoaringBitmap {
+	new := &RoaringBitmap{keys: slices.Clone(r.keys), containers: make([]container, len(r.containers))}
+	for i, c := range r.containers {
+		new.containers[i] = c.clone()
+	}
+	return new
+}
+
+// Optimize converts the containers to their most compact kind. It is worth
+// calling once a bitmap is built value by value, as run containers are
+// only created by AddRange and set operations otherwise.
+func (r *RoaringBitmap) Optimize() {
+	for i, c := range r.containers {
+		r.containers[i] = optimize(c)
+	}
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler interface. The bitmap is
+// serialized in the portable format of the Roaring bitmap specification
+// (https://github.com/RoaringBitmap/RoaringFormatSpec) so that it can be
+// read by other Roaring implementations.
+func (r *RoaringBitmap) MarshalBinary() ([]byte, error) {
+	n := len(r.containers)
+	runFlags := make([]byte, (n+7)/8)
+	hasRun := false
+	for i, c := range r.containers {
+		if _, ok := c.(runContainer); ok {
+			runFlags[i/8] |= 1 << uint(i%8)
+			hasRun = true
+		}
+	}
+
+	var out []byte
+	if hasRun {
+		out = binary.LittleEndian.AppendUint32(out, serialCookie|uint32(n-1)<<16)
+		out = append(out, runFlags...)
+	} else {
+		out = binary.LittleEndian.AppendUint32(out, serialCookieNoRun)
+		out = binary.LittleEndian.AppendUint32(out, uint32(n))
+	}
+	for i, c := range r.containers {
+		out = binary.LittleEndian.AppendUint16(out, r.keys[i])
+		out = binary.LittleEndian.AppendUint16(out, uint16(c.cardinality()-1))
+	}
+	if !hasRun || n >= noOffsetThreshold {
+		offset := len(out) + 4*n
+		for _, c := range r.containers {
+			out = binary.LittleEndian.AppendUint32(out, uint32(offset))
+			offset += serializedSize(c)
+		}
+	}
+	for _, c := range r.containers {
+		out = c.appendTo(out)
+	}
+	return out, nil
+}
+
+// roaringReader reads serialized bitmaps, the first read past the end of
+// data setting err
+type roaringReader struct {
+	data []byte
+	err  error
+}
+
+func (rd *roaringReader) next(n int) []byte {
+	if rd.err == nil && len(rd.data) < n {
+		rd.err = fmt.Errorf("%w: unexpected end of data", ErrInvalidBitmap)
+	}
+	if rd.err != nil {
+		return make([]byte, n)
+	}
+	b := rd.data[:n]
+	rd.data = rd.data[n:]
+	return b
+}
+
+func (rd *roaringReader) uint16() uint16 {
+	return binary.LittleEndian.Uint16(rd.next(2))
+}
+
+func (rd *roaringReader) uint32() uint32 {
+	return binary.LittleEndian.Uint32(rd.next(4))
+}
+
+// readContainer reads a container of card values
+func (rd *roaringReader) readContainer(card int, isRun bool) (container, error) {
+	switch {
+	case isRun:
+		r := make(runContainer, rd.uint16())
+		for i := range r {
+			start, length := int(rd.uint16()), int(rd.uint16())
+			if start+length > 1<<16-1 || i > 0 && start <= int(r[i-1].last) {
+				return nil, fmt.Errorf("%w: bad run %d", ErrInvalidBitmap, i)
+			}
+			r[i] = interval16{uint16(start), uint16(start + length)}
+		}
+		return r, nil
+	case card <= arrayMaxSize:
+		a := make(arrayContainer, card)
+		for i := range a {
+			if a[i] = rd.uint16(); i > 0 && a[i] <= a[i-1] {
+				return nil, fmt.Errorf("%w: unsorted array container", ErrInvalidBitmap)
+			}
+		}
+		return a, nil
+	}
+	b := &bitmapContainer{}
+	for i := range b.words {
+		b.words[i] = binary.LittleEndian.Uint64(rd.next(8))
+	}
+	b.count()
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler interface
+// (c.f. MarshalBinary)
+func (r *RoaringBitmap) UnmarshalBinary(data []byte) error {
+	rd := &roaringReader{data: data}
+	var n int
+	var runFlags []byte
+	switch cookie := rd.uint32(); {
+	case cookie&0xffff == serialCookie:
+		n = int(cookie>>16) + 1
+		runFlags = rd.next((n + 7) / 8)
+	case cookie == serialCookieNoRun:
+		if n = int(rd.uint32()); n > 1<<16 {
+			return fmt.Errorf("%w: %d containers", ErrInvalidBitmap, n)
+		}
+	default:
+		return fmt.Errorf("%w: bad cookie %#x", ErrInvalidBitmap, cookie)
+	}
+
+	keys, cards := make([]uint16, n), make([]int, n)
+	for i := range keys {
+		keys[i], cards[i] = rd.uint16(), int(rd.uint16())+1
+		if i > 0 && keys[i] <= keys[i-1] {
+			return fmt.Errorf("%w: unsorted keys", ErrInvalidBitmap)
+		}
+	}
+	// containers are stored in order so offsets are not needed
+	if runFlags == nil || n >= noOffsetThreshold {
+		rd.next(4 * n)
+	}
+
+	containers := make([]container, n)
+	for i := range containers {
+		isRun := runFlags != nil && runFlags[i/8]&(1<<uint(i%8)) != 0
+		c, err := rd.readContainer(cards[i], isRun)
+		if err != nil {
+			return err
+		}
+		if rd.err == nil && c.cardinality() != cards[i] {
+			return fmt.Errorf("%w: container %d holds %d values instead of %d", ErrInvalidBitmap, i, c.cardinality(), cards[i])
+		}
+		containers[i] = c
+	}
+	switch {
+	case rd.err != nil:
+		return rd.err
+	case len(rd.data) > 0:
+		return fmt.Errorf("%w: %d trailing bytes", ErrInvalidBitmap, len(rd.data))
+	}
+	r.keys, r.containers = keys, containers
+	return nil
+}
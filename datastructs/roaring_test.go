@@ -0,0 +1,193 @@
+package datastructs
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// checkBitmap checks that r holds exactly the values of ref
+func checkBitmap(t *testing.T, r *RoaringBitmap, ref map[uint32]bool) {
+	t.Helper()
+	if r.Cardinality() != uint64(len(ref)) {
+		t.Fatalf("Bad cardinality: %d instead of %d", r.Cardinality(), len(ref))
+	}
+	prev := int64(-1)
+	for x := range r.All() {
+		if !ref[x] || int64(x) <= prev {
+			t.Fatalf("Unexpected value %d after %d", x, prev)
+		}
+		prev = int64(x)
+	}
+	for x := range ref {
+		if !r.Contains(x) {
+			t.Fatalf("Missing value %d", x)
+		}
+	}
+}
+
+// randomBitmap returns a bitmap mixing sparse, dense and run containers and
+// the map of its values
+func randomBitmap(rng *rand.Rand) (*RoaringBitmap, map[uint32]bool) {
+	r, ref := NewRoaringBitmap(), make(map[uint32]bool)
+	add := func(x uint32) {
+		r.Add(x)
+		ref[x] = true
+	}
+	for i := 0; i < 1000; i++ {
+		add(rng.Uint32())
+	}
+	for i := 0; i < 10000; i++ {
+		add(1<<16 + uint32(rng.Intn(1<<16)))
+	}
+	start := uint32(rng.Intn(1 << 17))
+	r.AddRange(start, start+100000)
+	for x := start; x <= start+100000; x++ {
+		ref[x] = true
+	}
+	return r, ref
+}
+
+func TestRoaringBitmap(t *testing.T) {
+	r := NewRoaringBitmap(1, 1<<16, 1<<32-1)
+	ref := map[uint32]bool{1: true, 1 << 16: true, 1<<32 - 1: true}
+	checkBitmap(t, r, ref)
+	if r.Contains(2) || r.Contains(1<<16+1) {
+		t.Error("Unexpected value")
+	}
+
+	// array to bitmap and back
+	for x := uint32(0); x < 2*arrayMaxSize; x += 2 {
+		r.Add(x)
+		ref[x] = true
+	}
+	if _, ok := r.containers[0].(*bitmapContainer); !ok {
+		t.Errorf("Expecting a bitmap container: %T", r.containers[0])
+	}
+	checkBitmap(t, r, ref)
+	for x := uint32(0); x < arrayMaxSize; x += 2 {
+		r.Remove(x)
+		delete(ref, x)
+	}
+	if _, ok := r.containers[0].(arrayContainer); !ok {
+		t.Errorf("Expecting an array container: %T", r.containers[0])
+	}
+	checkBitmap(t, r, ref)
+
+	// runs
+	r.AddRange(1<<16-10, 3<<16+10)
+	for x := uint32(1<<16 - 10); x <= 3<<16+10; x++ {
+		ref[x] = true
+	}
+	if _, ok := r.containers[2].(runContainer); !ok {
+		t.Errorf("Expecting a run container: %T", r.containers[2])
+	}
+	for _, x := range []uint32{2<<16 + 5, 2<<16 + 7, 2<<16 + 6, 2 << 16, 3<<16 + 10} {
+		r.Remove(x)
+		delete(ref, x)
+	}
+	r.Add(2<<16 + 6)
+	ref[2<<16+6] = true
+	checkBitmap(t, r, ref)
+
+	for x := range ref {
+		r.Remove(x)
+	}
+	if !r.IsEmpty() || r.Cardinality() != 0 {
+		t.Error("Bitmap must be empty")
+	}
+}
+
+func TestRoaringBitmapOptimize(t *testing.T) {
+	r := NewRoaringBitmap()
+	for x := uint32(0); x < 50000; x++ {
+		r.Add(x)
+	}
+	if _, ok := r.containers[0].(*bitmapContainer); !ok {
+		t.Errorf("Expecting a bitmap container: %T", r.containers[0])
+	}
+	c := r.Copy()
+	r.Optimize()
+	if _, ok := r.containers[0].(runContainer); !ok {
+		t.Errorf("Expecting a run container: %T", r.containers[0])
+	}
+	if !r.Equal(c) || r.Cardinality() != 50000 {
+		t.Error("Optimizing must not change the values")
+	}
+}
+
+func TestRoaringBitmapSetOperations(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	a, refA := randomBitmap(rng)
+	b, refB := randomBitmap(rng)
+
+	expected := func(keep func(inA, inB bool) bool) map[uint32]bool {
+		ref := make(map[uint32]bool)
+		for _, m := range []map[uint32]bool{refA, refB} {
+			for x := range m {
+				if keep(refA[x], refB[x]) {
+					ref[x] = true
+				}
+			}
+		}
+		return ref
+	}
+
+	checkBitmap(t, a.Copy().And(b), expected(func(x, y bool) bool { return x && y }))
+	checkBitmap(t, a.Copy().Or(b), expected(func(x, y bool) bool { return x || y }))
+	checkBitmap(t, a.Copy().Xor(b), expected(func(x, y bool) bool { return x != y }))
+	checkBitmap(t, a.Copy().AndNot(b), expected(func(x, y bool) bool { return x && !y }))
+	// operands must be left untouched
+	checkBitmap(t, a, refA)
+	checkBitmap(t, b, refB)
+
+	if !a.Copy().Or(a).Equal(a) || !a.Copy().Xor(a).IsEmpty() || a.Equal(b) {
+		t.Error("Bad equality")
+	}
+}
+
+func TestRoaringBitmapSerialization(t *testing.T) {
+	// serialization of {1, 2, 3} given by the format specification
+	r := NewRoaringBitmap(1, 2, 3)
+	data, err := r.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{
+		0x3a, 0x30, 0, 0, 1, 0, 0, 0, // cookie and number of containers
+		0, 0, 2, 0, // key and cardinality - 1
+		16, 0, 0, 0, // offset
+		1, 0, 2, 0, 3, 0,
+	}
+	if !bytes.Equal(data, expected) {
+		t.Errorf("Bad serialization: %v", data)
+	}
+
+	rng := rand.New(rand.NewSource(42))
+	r, ref := randomBitmap(rng)
+	for _, optimize := range []bool{false, true} {
+		if optimize {
+			r.Optimize()
+		}
+		if data, err = r.MarshalBinary(); err != nil {
+			t.Fatal(err)
+		}
+		nr := NewRoaringBitmap()
+		if err := nr.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		checkBitmap(t, nr, ref)
+	}
+
+	for _, bad := range [][]byte{
+		data[:len(data)-1],
+		append(slices.Clone(data), 0),
+		{0xff, 0xff, 0, 0},
+	} {
+		if err := NewRoaringBitmap().UnmarshalBinary(bad); !errors.Is(err, ErrInvalidBitmap) {
+			t.Errorf("Expecting invalid bitmap error: %v", err)
+		}
+	}
+}
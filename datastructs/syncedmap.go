@@ -1,6 +1,10 @@
 package datastructs
 
-import "sync"
+import (
+	"sync"
+
+	"github.com/0xrawsec/golang-utils/datastructs/generic"
+)
 
 type SyncedMap struct {
 	sync.RWMutex
@@ -60,3 +64,13 @@ func (s *SyncedMap) Values() (values []interface{}) {
 func (s *SyncedMap) Len() int {
 	return len(s.m)
 }
+
+// ShardedMap is a thread safe map scaling better than SyncedMap with many
+// goroutines (c.f. generic.ShardedMap)
+type ShardedMap = generic.ShardedMap[interface{}, interface{}]
+
+// NewShardedMap returns an empty ShardedMap of shards shards (c.f.
+// generic.NewShardedMap)
+func NewShardedMap(shards int) *ShardedMap {
+	return generic.NewShardedMap[interface{}, interface{}](shards, nil)
+}
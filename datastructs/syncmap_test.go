@@ -79,3 +79,16 @@ func TestAdvancedSyncedMap(t *testing.T) {
 	}
 
 }
+
+func TestShardedMap(t *testing.T) {
+	sm := NewShardedMap(0)
+	sm.Add("foo", 2)
+	sm.Add(2, "foo")
+	if !sm.Contains(2) || !sm.Contains("foo") {
+		t.Error("Map should contain 2 and foo")
+	}
+	sm.Del(2)
+	if sm.Contains(2) || sm.Len() != 1 {
+		t.Error("Map should not contain 2")
+	}
+}
@@ -0,0 +1,210 @@
+// Package bingen generates the code encoding structures to binary without
+// reflection. Generated types implement encoding.Marshaler and
+// encoding.Unmarshaler so that encoding.Marshal and encoding.Unmarshal use
+// the generated code instead of reflection. They also implement the standard
+// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler interfaces.
+//
+// As the generator works with reflection, it has to be called from a program
+// (or a test) importing the types to generate code for. The generated file
+// must be part of the package defining those types.
+package bingen
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go/format"
+	"reflect"
+	"sort"
+
+	"github.com/0xrawsec/golang-utils/code/builder"
+	"github.com/0xrawsec/golang-utils/encoding"
+)
+
+const (
+	encodingPkg = "github.com/0xrawsec/golang-utils/encoding"
+)
+
+var (
+	// ErrNotStruct is returned when adding a value which is not a named
+	// structure
+	ErrNotStruct = errors.New("Not a named structure")
+	// ErrPackageMismatch is returned when adding types from different
+	// packages
+	ErrPackageMismatch = errors.New("Types must be defined in the same package")
+
+	marshalerType   = reflect.TypeOf((*encoding.Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*encoding.Unmarshaler)(nil)).Elem()
+)
+
+// UnsupportedError is returned when generating code for a field whose type
+// or bin tag options are not supported by the generator. Such types can
+// still be encoded with reflection by the encoding package.
+type UnsupportedError struct {
+	Type  reflect.Type
+	Field string
+	Msg   string
+}
+
+// Error implements error interface
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("Cannot generate code for %s.%s: %s", e.Type, e.Field, e.Msg)
+}
+
+// Generator generates binary encoding methods for structures
+type Generator struct {
+	// Order is the endianness used by the generated MarshalBinary and
+	// UnmarshalBinary methods, either binary.LittleEndian or binary.BigEndian
+	Order   encoding.Endianness
+	pkg     string
+	pkgPath string
+	types   []reflect.Type
+}
+
+// NewGenerator creates a new Generator of code for package pkg
+func NewGenerator(pkg string) *Generator {
+	return &Generator{Order: binary.LittleEndian, pkg: pkg}
+}
+
+// Add adds the types of values to the types to generate code for. Values
+// are named structures, or pointers to named structures, all defined in the
+// same package.
+func (g *Generator) Add(values ...interface{}) error {
+	for _, v := range values {
+		typ := reflect.TypeOf(v)
+		if typ != nil && typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		if typ == nil || typ.Kind() != reflect.Struct || typ.Name() == "" {
+			return fmt.Errorf("%w: %T", ErrNotStruct, v)
+		}
+		if g.pkgPath == "" {
+			g.pkgPath = typ.PkgPath()
+		}
+		if typ.PkgPath() != g.pkgPath {
+			return fmt.Errorf("%w: %s is not in %s", ErrPackageMismatch, typ, g.pkgPath)
+		}
+		g.types = append(g.types, typ)
+	}
+	return nil
+}
+
+// Generate returns the formatted source code of the methods of all the
+// types added to the generator
+func (g *Generator) Generate() ([]byte, error) {
+	order, ok := orderExpr(g.Order)
+	if !ok {
+		return nil, fmt.Errorf("Unsupported generator order %v", g.Order)
+	}
+
+	imports := map[string]bool{encodingPkg: true, "bytes": true, "encoding/binary": true, "io": true}
+	body := new(bytes.Buffer)
+	for _, typ := range g.types {
+		m := &method{g: g, typ: typ, imports: imports}
+		if err := m.writeEncode(body); err != nil {
+			return nil, err
+		}
+		if err := m.writeDecode(body); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(body, "\n// MarshalBinary implements encoding.BinaryMarshaler interface\n")
+		fmt.Fprintf(body, "func (v *%s) MarshalBinary() ([]byte, error) {\nreturn v.EncodeBinary(nil, %s)\n}\n", typ.Name(), order)
+		fmt.Fprintf(body, "\n// UnmarshalBinary implements encoding.BinaryUnmarshaler interface\n")
+		fmt.Fprintf(body, "func (v *%s) UnmarshalBinary(data []byte) error {\nreturn v.DecodeBinary(bytes.NewReader(data), %s)\n}\n", typ.Name(), order)
+	}
+
+	cb := builder.CodeBuilder{}
+	cb.WriteString("// Code generated by bingen. DO NOT EDIT.\n\n")
+	cb.Package(g.pkg)
+	paths := make([]string, 0, len(imports))
+	for path := range imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	// standard library imports first
+	cb.WriteString("\nimport (\n")
+	for _, path := range paths {
+		if path != encodingPkg {
+			fmt.Fprintf(&cb, "%q\n", path)
+		}
+	}
+	fmt.Fprintf(&cb, "\n%q\n)\n", encodingPkg)
+	cb.Write(body.Bytes())
+	return format.Source(cb.Bytes())
+}
+
+// known returns true if code is generated for typ or if typ already
+// implements the encoding interfaces
+func (g *Generator) known(typ reflect.Type) bool {
+	for _, t := range g.types {
+		if t == typ {
+			return true
+		}
+	}
+	ptr := reflect.PtrTo(typ)
+	return ptr.Implements(marshalerType) && ptr.Implements(unmarshalerType)
+}
+
+// typeName returns the name of typ in generated code
+func (g *Generator) typeName(typ reflect.Type) (string, bool) {
+	if typ.Name() != "" {
+		return typ.Name(), typ.PkgPath() == "" || typ.PkgPath() == g.pkgPath
+	}
+	switch typ.Kind() {
+	case reflect.Array:
+		elem, ok := g.typeName(typ.Elem())
+		return fmt.Sprintf("[%d]%s", typ.Len(), elem), ok
+	case reflect.Slice:
+		elem, ok := g.typeName(typ.Elem())
+		return "[]" + elem, ok
+	}
+	return "", false
+}
+
+func orderExpr(order encoding.Endianness) (string, bool) {
+	switch order {
+	case binary.LittleEndian:
+		return "binary.LittleEndian", true
+	case binary.BigEndian:
+		return "binary.BigEndian", true
+	}
+	return "", false
+}
+
+func isInteger(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func isUnsigned(k reflect.Kind) bool {
+	switch k {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// bits returns the size in bits of a numeric kind
+func bits(k reflect.Kind) int {
+	switch k {
+	case reflect.Bool, reflect.Int8, reflect.Uint8:
+		return 8
+	case reflect.Int16, reflect.Uint16:
+		return 16
+	case reflect.Int32, reflect.Uint32, reflect.Float32:
+		return 32
+	case reflect.Int64, reflect.Uint64, reflect.Float64:
+		return 64
+	}
+	return 0
+}
+
+// elemTag returns the tag applying to the elements of a slice or an array
+func elemTag(t *encoding.Tag) *encoding.Tag {
+	return &encoding.Tag{Varint: t.Varint, CString: t.CString}
+}
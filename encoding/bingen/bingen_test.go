@@ -0,0 +1,81 @@
+package bingen
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type unsupportedOffset struct {
+	Off  uint32
+	Data uint32 `bin:"offset=Off"`
+}
+
+type unsupportedUTF16 struct {
+	Name string `bin:"utf16le,cstring"`
+}
+
+type unsupportedMagic struct {
+	Magic uint32 `bin:"magic=0xcafe"`
+}
+
+type unsupportedComplex struct {
+	C complex64
+}
+
+type unknownStruct struct {
+	Nested struct{ A int32 }
+}
+
+type supported struct {
+	A uint16 `bin:"be"`
+	B []int8 `bin:"lenprefix=u8"`
+}
+
+func TestGenerate(t *testing.T) {
+	g := NewGenerator("bingen")
+	if err := g.Add(&supported{}); err != nil {
+		t.Fatal(err)
+	}
+	code, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"func (v *supported) EncodeBinary(",
+		"func (v *supported) DecodeBinary(",
+		"func (v *supported) MarshalBinary(",
+		"func (v *supported) UnmarshalBinary(",
+		"binary.BigEndian.PutUint16"} {
+		if !strings.Contains(string(code), s) {
+			t.Errorf("Generated code does not contain %q", s)
+		}
+	}
+	t.Log(string(code))
+}
+
+func TestGenerateErrors(t *testing.T) {
+	g := NewGenerator("bingen")
+	if err := g.Add(42); !errors.Is(err, ErrNotStruct) {
+		t.Errorf("Expecting not a struct error: %v", err)
+	}
+	if err := g.Add(struct{}{}); !errors.Is(err, ErrNotStruct) {
+		t.Errorf("Expecting not a struct error: %v", err)
+	}
+	if err := g.Add(supported{}, strings.Builder{}); !errors.Is(err, ErrPackageMismatch) {
+		t.Errorf("Expecting package mismatch: %v", err)
+	}
+
+	for _, v := range []interface{}{unsupportedOffset{}, unsupportedUTF16{}, unsupportedMagic{}, unsupportedComplex{}, unknownStruct{}} {
+		g := NewGenerator("bingen")
+		if err := g.Add(v); err != nil {
+			t.Fatal(err)
+		}
+		var uerr *UnsupportedError
+		if _, err := g.Generate(); !errors.As(err, &uerr) {
+			t.Errorf("Expecting unsupported error for %T: %v", v, err)
+		} else {
+			t.Log(err)
+		}
+	}
+}
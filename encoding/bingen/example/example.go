@@ -0,0 +1,49 @@
+// Package example contains structures whose binary encoding methods are
+// generated by bingen
+package example
+
+//go:generate go test -run TestGenerated -update
+
+// Kind of a Record
+type Kind uint16
+
+// Point is a point in space
+type Point struct {
+	X, Y, Z float64
+}
+
+// Record is a structure using most of the options supported by bingen
+type Record struct {
+	Magic    [4]byte
+	Kind     Kind `bin:"be"`
+	Flags    uint8
+	Valid    bool
+	ID       int64  `bin:"sleb128"`
+	Size     uint32 `bin:"uleb128"`
+	Ratio    float32
+	Position Point
+	Name     string `bin:"lenprefix=u8"`
+	Label    string `bin:"size=8"`
+	Comment  string `bin:"cstring"`
+	Data     []byte `bin:"lenprefix=uleb128"`
+	Count    uint16 `bin:"-"`
+	NValues  uint16
+	Values   []int32  `bin:"len=NValues"`
+	Tags     []string `bin:"cstring,lenprefix=u16"`
+	Path     []Point  `bin:"size=2"`
+	Deltas   []int64  `bin:"sleb128"`
+}
+
+// Event is a flat structure used to compare generated and reflect based
+// encoding
+type Event struct {
+	Timestamp int64
+	PID       uint32
+	TID       uint32
+	EventID   uint16
+	Level     uint8
+	Opcode    uint8
+	Keywords  uint64
+	Image     string `bin:"lenprefix=u16"`
+	Payload   []byte `bin:"lenprefix=u32"`
+}
@@ -0,0 +1,396 @@
+// Code generated by bingen. DO NOT EDIT.
+
+package example
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unsafe"
+
+	"github.com/0xrawsec/golang-utils/encoding"
+)
+
+// EncodeBinary implements encoding.Marshaler interface
+func (v *Point) EncodeBinary(b []byte, order encoding.Endianness) ([]byte, error) {
+	var s [8]byte
+	order.PutUint64(s[:], math.Float64bits(float64(v.X)))
+	b = append(b, s[:8]...)
+	order.PutUint64(s[:], math.Float64bits(float64(v.Y)))
+	b = append(b, s[:8]...)
+	order.PutUint64(s[:], math.Float64bits(float64(v.Z)))
+	b = append(b, s[:8]...)
+	return b, nil
+}
+
+// DecodeBinary implements encoding.Unmarshaler interface
+func (v *Point) DecodeBinary(r io.Reader, order encoding.Endianness) error {
+	var s [8]byte
+	if _, err := io.ReadFull(r, s[:8]); err != nil {
+		return err
+	}
+	v.X = math.Float64frombits(order.Uint64(s[:]))
+	if _, err := io.ReadFull(r, s[:8]); err != nil {
+		return err
+	}
+	v.Y = math.Float64frombits(order.Uint64(s[:]))
+	if _, err := io.ReadFull(r, s[:8]); err != nil {
+		return err
+	}
+	v.Z = math.Float64frombits(order.Uint64(s[:]))
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler interface
+func (v *Point) MarshalBinary() ([]byte, error) {
+	return v.EncodeBinary(nil, binary.LittleEndian)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler interface
+func (v *Point) UnmarshalBinary(data []byte) error {
+	return v.DecodeBinary(bytes.NewReader(data), binary.LittleEndian)
+}
+
+// EncodeBinary implements encoding.Marshaler interface
+func (v *Record) EncodeBinary(b []byte, order encoding.Endianness) ([]byte, error) {
+	var s [8]byte
+	var err error
+	b = append(b, v.Magic[:]...)
+	binary.BigEndian.PutUint16(s[:], uint16(v.Kind))
+	b = append(b, s[:2]...)
+	b = append(b, byte(v.Flags))
+	if v.Valid {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	b = encoding.AppendVarint(b, int64(v.ID))
+	b = encoding.AppendUvarint(b, uint64(v.Size))
+	order.PutUint32(s[:], math.Float32bits(float32(v.Ratio)))
+	b = append(b, s[:4]...)
+	if b, err = v.Position.EncodeBinary(b, order); err != nil {
+		return b, err
+	}
+	if uint64(len(v.Name)) > math.MaxUint8 {
+		return b, fmt.Errorf("%w: %d does not fit in uint8", encoding.ErrLengthOverflow, len(v.Name))
+	}
+	b = append(b, byte(len(v.Name)))
+	b = append(b, v.Name...)
+	if len(v.Label) > 8 {
+		return b, fmt.Errorf("%w: string of length %d while size is 8", encoding.ErrLengthOverflow, len(v.Label))
+	}
+	b = append(b, v.Label...)
+	b = append(b, make([]byte, 8-len(v.Label))...)
+	if strings.IndexByte(string(v.Comment), 0) >= 0 {
+		return b, fmt.Errorf("%w: %q", encoding.ErrNulInString, v.Comment)
+	}
+	b = append(b, v.Comment...)
+	b = append(b, 0)
+	b = encoding.AppendUvarint(b, uint64(len(v.Data)))
+	b = append(b, v.Data...)
+	order.PutUint16(s[:], uint16(v.NValues))
+	b = append(b, s[:2]...)
+	if int64(len(v.Values)) != int64(v.NValues) {
+		return b, fmt.Errorf("%w: Values has length %d while NValues is %d", encoding.ErrLengthMismatch, len(v.Values), v.NValues)
+	}
+	for i1 := range v.Values {
+		order.PutUint32(s[:], uint32(v.Values[i1]))
+		b = append(b, s[:4]...)
+	}
+	if uint64(len(v.Tags)) > math.MaxUint16 {
+		return b, fmt.Errorf("%w: %d does not fit in uint16", encoding.ErrLengthOverflow, len(v.Tags))
+	}
+	order.PutUint16(s[:], uint16(len(v.Tags)))
+	b = append(b, s[:2]...)
+	for i2 := range v.Tags {
+		if strings.IndexByte(string(v.Tags[i2]), 0) >= 0 {
+			return b, fmt.Errorf("%w: %q", encoding.ErrNulInString, v.Tags[i2])
+		}
+		b = append(b, v.Tags[i2]...)
+		b = append(b, 0)
+	}
+	if len(v.Path) > 2 {
+		return b, fmt.Errorf("%w: slice of length %d while size is 2", encoding.ErrLengthOverflow, len(v.Path))
+	}
+	for i3 := range v.Path {
+		if b, err = v.Path[i3].EncodeBinary(b, order); err != nil {
+			return b, err
+		}
+	}
+	if pad4 := 2 - len(v.Path); pad4 > 0 {
+		var zero5 Point
+		for i6 := 0; i6 < pad4; i6++ {
+			if b, err = zero5.EncodeBinary(b, order); err != nil {
+				return b, err
+			}
+		}
+	}
+	order.PutUint64(s[:], uint64(len(v.Deltas)))
+	b = append(b, s[:8]...)
+	for i7 := range v.Deltas {
+		b = encoding.AppendVarint(b, int64(v.Deltas[i7]))
+	}
+	return b, nil
+}
+
+// DecodeBinary implements encoding.Unmarshaler interface
+func (v *Record) DecodeBinary(r io.Reader, order encoding.Endianness) error {
+	var s [8]byte
+	if _, err := io.ReadFull(r, v.Magic[:]); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r, s[:2]); err != nil {
+		return err
+	}
+	v.Kind = Kind(binary.BigEndian.Uint16(s[:]))
+	if _, err := io.ReadFull(r, s[:1]); err != nil {
+		return err
+	}
+	v.Flags = s[0]
+	if _, err := io.ReadFull(r, s[:1]); err != nil {
+		return err
+	}
+	v.Valid = s[0] != 0
+	x8, err := encoding.ReadVarint(r)
+	if err != nil {
+		return err
+	}
+	v.ID = int64(x8)
+	x9, err := encoding.ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+	if x9 > math.MaxUint32 {
+		return fmt.Errorf("%w: %d does not fit in uint32", encoding.ErrVarintOverflow, x9)
+	}
+	v.Size = uint32(x9)
+	if _, err := io.ReadFull(r, s[:4]); err != nil {
+		return err
+	}
+	v.Ratio = math.Float32frombits(order.Uint32(s[:]))
+	if err := v.Position.DecodeBinary(r, order); err != nil {
+		return err
+	}
+	var n11 int
+	if _, err := io.ReadFull(r, s[:1]); err != nil {
+		return err
+	}
+	n11 = int(s[0])
+	if err := encoding.CheckLength(r, n11, 1); err != nil {
+		return err
+	}
+	buf10, err := encoding.ReadBytes(r, n11)
+	if err != nil {
+		return err
+	}
+	v.Name = string(buf10)
+	n13 := 8
+	if err := encoding.CheckLength(r, n13, 1); err != nil {
+		return err
+	}
+	buf12, err := encoding.ReadBytes(r, n13)
+	if err != nil {
+		return err
+	}
+	if i := bytes.IndexByte(buf12, 0); i >= 0 {
+		buf12 = buf12[:i]
+	}
+	v.Label = string(buf12)
+	buf14, err := encoding.ReadTerminated(r)
+	if err != nil {
+		return err
+	}
+	v.Comment = string(buf14)
+	x16, err := encoding.ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+	n15 := int(x16)
+	if err := encoding.CheckLength(r, n15, int(unsafe.Sizeof(v.Data[0]))); err != nil {
+		return err
+	}
+	buf17, err := encoding.ReadBytes(r, n15)
+	if err != nil {
+		return err
+	}
+	v.Data = []uint8(buf17)
+	if _, err := io.ReadFull(r, s[:2]); err != nil {
+		return err
+	}
+	v.NValues = order.Uint16(s[:])
+	n18 := int(v.NValues)
+	if err := encoding.CheckLength(r, n18, int(unsafe.Sizeof(v.Values[0]))); err != nil {
+		return err
+	}
+	v.Values = make([]int32, 0, encoding.CapHint(n18, int(unsafe.Sizeof(v.Values[0]))))
+	for i19 := 0; i19 < n18; i19++ {
+		var e20 int32
+		if _, err := io.ReadFull(r, s[:4]); err != nil {
+			return err
+		}
+		e20 = int32(order.Uint32(s[:]))
+		v.Values = append(v.Values, e20)
+	}
+	var n21 int
+	if _, err := io.ReadFull(r, s[:2]); err != nil {
+		return err
+	}
+	n21 = int(order.Uint16(s[:]))
+	if err := encoding.CheckLength(r, n21, int(unsafe.Sizeof(v.Tags[0]))); err != nil {
+		return err
+	}
+	v.Tags = make([]string, 0, encoding.CapHint(n21, int(unsafe.Sizeof(v.Tags[0]))))
+	for i22 := 0; i22 < n21; i22++ {
+		var e23 string
+		buf24, err := encoding.ReadTerminated(r)
+		if err != nil {
+			return err
+		}
+		e23 = string(buf24)
+		v.Tags = append(v.Tags, e23)
+	}
+	n25 := 2
+	if err := encoding.CheckLength(r, n25, int(unsafe.Sizeof(v.Path[0]))); err != nil {
+		return err
+	}
+	v.Path = make([]Point, 0, encoding.CapHint(n25, int(unsafe.Sizeof(v.Path[0]))))
+	for i26 := 0; i26 < n25; i26++ {
+		var e27 Point
+		if err := e27.DecodeBinary(r, order); err != nil {
+			return err
+		}
+		v.Path = append(v.Path, e27)
+	}
+	var n28 int
+	if _, err := io.ReadFull(r, s[:8]); err != nil {
+		return err
+	}
+	n28 = int(int64(order.Uint64(s[:])))
+	if err := encoding.CheckLength(r, n28, int(unsafe.Sizeof(v.Deltas[0]))); err != nil {
+		return err
+	}
+	v.Deltas = make([]int64, 0, encoding.CapHint(n28, int(unsafe.Sizeof(v.Deltas[0]))))
+	for i29 := 0; i29 < n28; i29++ {
+		var e30 int64
+		x31, err := encoding.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		e30 = int64(x31)
+		v.Deltas = append(v.Deltas, e30)
+	}
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler interface
+func (v *Record) MarshalBinary() ([]byte, error) {
+	return v.EncodeBinary(nil, binary.LittleEndian)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler interface
+func (v *Record) UnmarshalBinary(data []byte) error {
+	return v.DecodeBinary(bytes.NewReader(data), binary.LittleEndian)
+}
+
+// EncodeBinary implements encoding.Marshaler interface
+func (v *Event) EncodeBinary(b []byte, order encoding.Endianness) ([]byte, error) {
+	var s [8]byte
+	order.PutUint64(s[:], uint64(v.Timestamp))
+	b = append(b, s[:8]...)
+	order.PutUint32(s[:], uint32(v.PID))
+	b = append(b, s[:4]...)
+	order.PutUint32(s[:], uint32(v.TID))
+	b = append(b, s[:4]...)
+	order.PutUint16(s[:], uint16(v.EventID))
+	b = append(b, s[:2]...)
+	b = append(b, byte(v.Level))
+	b = append(b, byte(v.Opcode))
+	order.PutUint64(s[:], uint64(v.Keywords))
+	b = append(b, s[:8]...)
+	if uint64(len(v.Image)) > math.MaxUint16 {
+		return b, fmt.Errorf("%w: %d does not fit in uint16", encoding.ErrLengthOverflow, len(v.Image))
+	}
+	order.PutUint16(s[:], uint16(len(v.Image)))
+	b = append(b, s[:2]...)
+	b = append(b, v.Image...)
+	if uint64(len(v.Payload)) > math.MaxUint32 {
+		return b, fmt.Errorf("%w: %d does not fit in uint32", encoding.ErrLengthOverflow, len(v.Payload))
+	}
+	order.PutUint32(s[:], uint32(len(v.Payload)))
+	b = append(b, s[:4]...)
+	b = append(b, v.Payload...)
+	return b, nil
+}
+
+// DecodeBinary implements encoding.Unmarshaler interface
+func (v *Event) DecodeBinary(r io.Reader, order encoding.Endianness) error {
+	var s [8]byte
+	if _, err := io.ReadFull(r, s[:8]); err != nil {
+		return err
+	}
+	v.Timestamp = int64(order.Uint64(s[:]))
+	if _, err := io.ReadFull(r, s[:4]); err != nil {
+		return err
+	}
+	v.PID = order.Uint32(s[:])
+	if _, err := io.ReadFull(r, s[:4]); err != nil {
+		return err
+	}
+	v.TID = order.Uint32(s[:])
+	if _, err := io.ReadFull(r, s[:2]); err != nil {
+		return err
+	}
+	v.EventID = order.Uint16(s[:])
+	if _, err := io.ReadFull(r, s[:1]); err != nil {
+		return err
+	}
+	v.Level = s[0]
+	if _, err := io.ReadFull(r, s[:1]); err != nil {
+		return err
+	}
+	v.Opcode = s[0]
+	if _, err := io.ReadFull(r, s[:8]); err != nil {
+		return err
+	}
+	v.Keywords = order.Uint64(s[:])
+	var n2 int
+	if _, err := io.ReadFull(r, s[:2]); err != nil {
+		return err
+	}
+	n2 = int(order.Uint16(s[:]))
+	if err := encoding.CheckLength(r, n2, 1); err != nil {
+		return err
+	}
+	buf1, err := encoding.ReadBytes(r, n2)
+	if err != nil {
+		return err
+	}
+	v.Image = string(buf1)
+	var n3 int
+	if _, err := io.ReadFull(r, s[:4]); err != nil {
+		return err
+	}
+	n3 = int(order.Uint32(s[:]))
+	if err := encoding.CheckLength(r, n3, int(unsafe.Sizeof(v.Payload[0]))); err != nil {
+		return err
+	}
+	buf4, err := encoding.ReadBytes(r, n3)
+	if err != nil {
+		return err
+	}
+	v.Payload = []uint8(buf4)
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler interface
+func (v *Event) MarshalBinary() ([]byte, error) {
+	return v.EncodeBinary(nil, binary.LittleEndian)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler interface
+func (v *Event) UnmarshalBinary(data []byte) error {
+	return v.DecodeBinary(bytes.NewReader(data), binary.LittleEndian)
+}
@@ -0,0 +1,237 @@
+package example
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/0xrawsec/golang-utils/encoding"
+	"github.com/0xrawsec/golang-utils/encoding/bingen"
+)
+
+const (
+	generated = "example_bin.go"
+)
+
+var (
+	update = flag.Bool("update", false, "update generated code")
+)
+
+// types without generated methods, encoded with reflection
+type (
+	plainRecord Record
+	plainEvent  Event
+)
+
+func newRecord() Record {
+	return Record{
+		Magic:    [4]byte{'R', 'E', 'C', '0'},
+		Kind:     42,
+		Flags:    0x81,
+		Valid:    true,
+		ID:       -123456,
+		Size:     624485,
+		Ratio:    0.5,
+		Position: Point{1, 2, 3},
+		Name:     "record",
+		Label:    "label",
+		Comment:  "comment",
+		Data:     []byte("data"),
+		NValues:  3,
+		Values:   []int32{-1, 0, 1},
+		Tags:     []string{"foo", "bar"},
+		Path:     []Point{{4, 5, 6}, {7, 8, 9}},
+		Deltas:   []int64{-64, 63, 1 << 40}}
+}
+
+func newEvent() Event {
+	return Event{
+		Timestamp: 1600000000,
+		PID:       4,
+		TID:       1234,
+		EventID:   1,
+		Level:     4,
+		Keywords:  0x8000000000000000,
+		Image:     `C:\Windows\System32\svchost.exe`,
+		Payload:   bytes.Repeat([]byte{0x41}, 64)}
+}
+
+func TestGenerated(t *testing.T) {
+	g := bingen.NewGenerator("example")
+	if err := g.Add(Point{}, &Record{}, Event{}); err != nil {
+		t.Fatal(err)
+	}
+	code, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := ioutil.WriteFile(generated, code, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	current, err := ioutil.ReadFile(generated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(code, current) {
+		t.Errorf("%s is outdated, run go generate", generated)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, order := range []encoding.Endianness{binary.LittleEndian, binary.BigEndian} {
+		r := newRecord()
+		fast, err := encoding.Marshal(&r, order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slow, err := encoding.Marshal((*plainRecord)(&r), order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(fast, slow) {
+			t.Fatalf("Generated and reflect encodings differ:\n%v\n%v", fast, slow)
+		}
+
+		var fr, sr Record
+		if err := encoding.Unmarshal(bytes.NewReader(fast), &fr, order); err != nil {
+			t.Fatal(err)
+		}
+		if err := encoding.Unmarshal(bytes.NewReader(fast), (*plainRecord)(&sr), order); err != nil {
+			t.Fatal(err)
+		}
+		// skipped field is not encoded
+		r.Count = 0
+		if !reflect.DeepEqual(r, fr) || !reflect.DeepEqual(r, sr) {
+			t.Errorf("Bad decoding:\n%+v\n%+v", fr, sr)
+		}
+	}
+}
+
+func TestBinaryMarshaler(t *testing.T) {
+	e := newEvent()
+	data, err := e.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := encoding.Marshal((*plainEvent)(&e), binary.LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, expected) {
+		t.Fatal("Bad encoding")
+	}
+	var ne Event
+	if err := ne.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(e, ne) {
+		t.Errorf("Bad decoding: %+v", ne)
+	}
+}
+
+func TestGeneratedErrors(t *testing.T) {
+	r := newRecord()
+	r.NValues = 2
+	if _, err := r.MarshalBinary(); !errors.Is(err, encoding.ErrLengthMismatch) {
+		t.Errorf("Expecting length mismatch: %v", err)
+	}
+	r = newRecord()
+	r.Comment = "foo\x00bar"
+	if _, err := r.MarshalBinary(); !errors.Is(err, encoding.ErrNulInString) {
+		t.Errorf("Expecting NUL in string error: %v", err)
+	}
+	r = newRecord()
+	r.Path = make([]Point, 3)
+	if _, err := r.MarshalBinary(); !errors.Is(err, encoding.ErrLengthOverflow) {
+		t.Errorf("Expecting length overflow: %v", err)
+	}
+	r = newRecord()
+	data, _ := r.MarshalBinary()
+	if err := r.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Error("Expecting truncated data error")
+	}
+}
+
+func BenchmarkMarshalGenerated(b *testing.B) {
+	e := newEvent()
+	for i := 0; i < b.N; i++ {
+		if _, err := encoding.Marshal(&e, binary.LittleEndian); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalReflect(b *testing.B) {
+	e := newEvent()
+	for i := 0; i < b.N; i++ {
+		if _, err := encoding.Marshal((*plainEvent)(&e), binary.LittleEndian); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalGenerated(b *testing.B) {
+	e := newEvent()
+	data, _ := e.MarshalBinary()
+	r := bytes.NewReader(data)
+	for i := 0; i < b.N; i++ {
+		r.Reset(data)
+		if err := encoding.Unmarshal(r, &e, binary.LittleEndian); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalReflect(b *testing.B) {
+	e := newEvent()
+	data, _ := e.MarshalBinary()
+	r := bytes.NewReader(data)
+	for i := 0; i < b.N; i++ {
+		r.Reset(data)
+		if err := encoding.Unmarshal(r, (*plainEvent)(&e), binary.LittleEndian); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGeneratedLimits(t *testing.T) {
+	r := newRecord()
+	data, err := r.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := encoding.NewDecoder(bytes.NewReader(data), binary.LittleEndian)
+	dec.SetLimits(encoding.Limits{MaxSliceLen: 2})
+	if err := dec.Decode(&r); !errors.Is(err, encoding.ErrLimitExceeded) {
+		t.Errorf("Expecting limit error: %v", err)
+	}
+
+	// unterminated string longer than the limit
+	r = newRecord()
+	n := 1000
+	r.Comment = string(bytes.Repeat([]byte{'c'}, n))
+	data, _ = r.MarshalBinary()
+	for _, l := range []int{n - 1, n} {
+		dec = encoding.NewDecoder(bytes.NewReader(data), binary.LittleEndian)
+		dec.SetLimits(encoding.Limits{MaxSliceLen: l})
+		if err := dec.Decode(&r); (l < n) != errors.Is(err, encoding.ErrLimitExceeded) {
+			t.Errorf("Unexpected error with limit %d: %v", l, err)
+		}
+	}
+
+	// huge length of Payload with few data
+	e := newEvent()
+	data, _ = e.MarshalBinary()
+	binary.LittleEndian.PutUint32(data[len(data)-len(e.Payload)-4:], 1<<31)
+	if err := e.UnmarshalBinary(data); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expecting unexpected EOF: %v", err)
+	}
+}
@@ -0,0 +1,514 @@
+package bingen
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/0xrawsec/golang-utils/encoding"
+)
+
+// method generates the encoding or decoding method of a structure
+type method struct {
+	g       *Generator
+	typ     reflect.Type
+	imports map[string]bool
+	buf     bytes.Buffer
+	// field being generated, used in errors
+	field string
+	// number of local variables declared so far
+	locals int
+	// scratch is true if the method uses the scratch buffer
+	scratch bool
+	// err is true if the method uses a function scoped error
+	err bool
+}
+
+func (m *method) printf(format string, a ...interface{}) {
+	fmt.Fprintf(&m.buf, format, a...)
+}
+
+func (m *method) local(prefix string) string {
+	m.locals++
+	return fmt.Sprintf("%s%d", prefix, m.locals)
+}
+
+func (m *method) unsupported(format string, a ...interface{}) error {
+	return &UnsupportedError{m.typ, m.field, fmt.Sprintf(format, a...)}
+}
+
+func (m *method) typeName(typ reflect.Type) (string, error) {
+	name, ok := m.g.typeName(typ)
+	if !ok {
+		return "", m.unsupported("type %s is not defined in %s", typ, m.g.pkgPath)
+	}
+	return name, nil
+}
+
+// fields calls fn for every encoded field of the structure
+func (m *method) fields(fn func(expr string, typ reflect.Type, t *encoding.Tag) error) error {
+	for i := 0; i < m.typ.NumField(); i++ {
+		sf := m.typ.Field(i)
+		m.field = sf.Name
+		t, err := encoding.ParseTag(sf.Tag.Get(encoding.TagName))
+		if err != nil {
+			return m.unsupported("%s", err)
+		}
+		if t.Skip {
+			continue
+		}
+		if !sf.IsExported() {
+			return m.unsupported("unexported and padding fields are not supported")
+		}
+		if t.Offset >= 0 || t.OffsetField != "" {
+			return m.unsupported("offset option is not supported")
+		}
+		if t.UTF16 {
+			return m.unsupported("utf16 options are not supported")
+		}
+		if t.Bits > 0 {
+			return m.unsupported("bits option is not supported")
+		}
+		if t.Magic != "" || t.CRC32Start != "" {
+			return m.unsupported("magic and crc32 options are not supported")
+		}
+		if t.LenField != "" {
+			lf, ok := m.typ.FieldByName(t.LenField)
+			if !ok || lf.Index[0] >= i || !isInteger(lf.Type.Kind()) {
+				return m.unsupported("length field %s must be a previous integer field", t.LenField)
+			}
+		}
+		if err := fn("v."+sf.Name, sf.Type, &t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write writes the method with its local declarations to w
+func (m *method) write(w *bytes.Buffer, doc, signature, ret string) {
+	fmt.Fprintf(w, "\n// %s\nfunc (v *%s) %s {\n", doc, m.typ.Name(), signature)
+	if m.scratch {
+		w.WriteString("var s [8]byte\n")
+	}
+	if m.err {
+		w.WriteString("var err error\n")
+	}
+	w.Write(m.buf.Bytes())
+	fmt.Fprintf(w, "return %s\n}\n", ret)
+}
+
+func (m *method) writeEncode(w *bytes.Buffer) error {
+	err := m.fields(func(expr string, typ reflect.Type, t *encoding.Tag) error {
+		return m.encode(expr, typ, t, "order")
+	})
+	if err != nil {
+		return err
+	}
+	m.write(w, "EncodeBinary implements encoding.Marshaler interface",
+		"EncodeBinary(b []byte, order encoding.Endianness) ([]byte, error)", "b, nil")
+	return nil
+}
+
+func (m *method) writeDecode(w *bytes.Buffer) error {
+	m.buf.Reset()
+	m.scratch, m.err = false, false
+	err := m.fields(func(expr string, typ reflect.Type, t *encoding.Tag) error {
+		return m.decode(expr, typ, t, "order")
+	})
+	if err != nil {
+		return err
+	}
+	m.write(w, "DecodeBinary implements encoding.Unmarshaler interface",
+		"DecodeBinary(r io.Reader, order encoding.Endianness) error", "nil")
+	return nil
+}
+
+// fieldOrder returns the endianness expression to use for a field
+func (m *method) fieldOrder(t *encoding.Tag, order string) string {
+	if t.Order != nil {
+		if expr, ok := orderExpr(t.Order); ok {
+			return expr
+		}
+	}
+	return order
+}
+
+// check writes a statement returning an error if cond is true
+func (m *method) check(ret, cond, errVar, format string, a ...string) {
+	m.imports["fmt"] = true
+	args := ""
+	if len(a) > 0 {
+		args = ", " + strings.Join(a, ", ")
+	}
+	m.printf("if %s {\nreturn %sfmt.Errorf(\"%%w: %s\", encoding.%s%s)\n}\n", cond, ret, format, errVar, args)
+}
+
+/////////////////////////////////// Encoding ///////////////////////////////////
+
+func (m *method) encode(expr string, typ reflect.Type, t *encoding.Tag, order string) error {
+	order = m.fieldOrder(t, order)
+	kind := typ.Kind()
+
+	if t.Varint != encoding.NoVarint && isInteger(kind) {
+		return m.encodeVarint(expr, kind, t.Varint)
+	}
+
+	switch kind {
+	case reflect.Struct:
+		if !m.g.known(typ) {
+			return m.unsupported("no code generated for %s", typ)
+		}
+		m.err = true
+		m.printf("if b, err = %s.EncodeBinary(b, %s); err != nil {\nreturn b, err\n}\n", expr, order)
+	case reflect.Array:
+		return m.encodeElements(expr, typ, t, order)
+	case reflect.Slice:
+		return m.encodeSlice(expr, typ, t, order)
+	case reflect.String:
+		return m.encodeString(expr, t, order)
+	default:
+		return m.encodePrimitive(expr, kind, order)
+	}
+	return nil
+}
+
+func (m *method) encodeVarint(expr string, kind reflect.Kind, varint encoding.Varint) error {
+	switch {
+	case varint == encoding.Uleb128 && !isUnsigned(kind):
+		m.check("b, ", expr+" < 0", "ErrVarintOverflow", "cannot encode negative value %d as uleb128", expr)
+		fallthrough
+	case varint == encoding.Uleb128:
+		m.printf("b = encoding.AppendUvarint(b, uint64(%s))\n", expr)
+	default:
+		if kind == reflect.Uint64 {
+			m.imports["math"] = true
+			m.check("b, ", expr+" > math.MaxInt64", "ErrVarintOverflow", "cannot encode %d as sleb128", expr)
+		}
+		m.printf("b = encoding.AppendVarint(b, int64(%s))\n", expr)
+	}
+	return nil
+}
+
+func (m *method) encodeElements(expr string, typ reflect.Type, t *encoding.Tag, order string) error {
+	if typ.Elem().Kind() == reflect.Uint8 && t.Varint == encoding.NoVarint {
+		if typ.Kind() == reflect.Array {
+			expr += "[:]"
+		}
+		m.printf("b = append(b, %s...)\n", expr)
+		return nil
+	}
+	i := m.local("i")
+	m.printf("for %s := range %s {\n", i, expr)
+	if err := m.encode(fmt.Sprintf("%s[%s]", expr, i), typ.Elem(), elemTag(t), order); err != nil {
+		return err
+	}
+	m.printf("}\n")
+	return nil
+}
+
+// encodeLength writes the checks and the length prefix of a slice or string
+func (m *method) encodeLength(expr, what string, t *encoding.Tag, order string) error {
+	switch {
+	case t.Size > 0:
+		m.check("b, ", fmt.Sprintf("len(%s) > %d", expr, t.Size), "ErrLengthOverflow",
+			fmt.Sprintf("%s of length %%d while size is %d", what, t.Size), "len("+expr+")")
+	case t.LenField != "":
+		m.check("b, ", fmt.Sprintf("int64(len(%s)) != int64(v.%s)", expr, t.LenField), "ErrLengthMismatch",
+			fmt.Sprintf("%s has length %%d while %s is %%d", strings.TrimPrefix(expr, "v."), t.LenField), "len("+expr+")", "v."+t.LenField)
+	case t.LenVarint:
+		m.printf("b = encoding.AppendUvarint(b, uint64(len(%s)))\n", expr)
+	default:
+		kind := t.LenPrefix
+		if kind == reflect.Invalid {
+			kind = reflect.Int64
+		}
+		if n := bits(kind); n < 64 {
+			max := fmt.Sprintf("math.MaxInt%d", n)
+			if isUnsigned(kind) {
+				max = fmt.Sprintf("math.MaxUint%d", n)
+			}
+			m.imports["math"] = true
+			m.check("b, ", fmt.Sprintf("uint64(len(%s)) > %s", expr, max), "ErrLengthOverflow",
+				fmt.Sprintf("%%d does not fit in %s", kind), "len("+expr+")")
+		}
+		return m.encodePrimitive(fmt.Sprintf("len(%s)", expr), kind, order)
+	}
+	return nil
+}
+
+func (m *method) encodeSlice(expr string, typ reflect.Type, t *encoding.Tag, order string) error {
+	if err := m.encodeLength(expr, "slice", t, order); err != nil {
+		return err
+	}
+	if err := m.encodeElements(expr, typ, t, order); err != nil {
+		return err
+	}
+	if t.Size > 0 {
+		// zero padding up to size
+		elem, err := m.typeName(typ.Elem())
+		if err != nil {
+			return err
+		}
+		pad := m.local("pad")
+		m.printf("if %s := %d - len(%s); %s > 0 {\n", pad, t.Size, expr, pad)
+		if typ.Elem().Kind() == reflect.Uint8 && t.Varint == encoding.NoVarint {
+			m.printf("b = append(b, make([]byte, %s)...)\n", pad)
+		} else {
+			zero, i := m.local("zero"), m.local("i")
+			m.printf("var %s %s\nfor %s := 0; %s < %s; %s++ {\n", zero, elem, i, i, pad, i)
+			if err := m.encode(zero, typ.Elem(), elemTag(t), order); err != nil {
+				return err
+			}
+			m.printf("}\n")
+		}
+		m.printf("}\n")
+	}
+	return nil
+}
+
+func (m *method) encodeString(expr string, t *encoding.Tag, order string) error {
+	if t.CString {
+		m.imports["strings"] = true
+		m.check("b, ", fmt.Sprintf("strings.IndexByte(string(%s), 0) >= 0", expr), "ErrNulInString", "%q", expr)
+		m.printf("b = append(b, %s...)\nb = append(b, 0)\n", expr)
+		return nil
+	}
+	if err := m.encodeLength(expr, "string", t, order); err != nil {
+		return err
+	}
+	m.printf("b = append(b, %s...)\n", expr)
+	if t.Size > 0 {
+		m.printf("b = append(b, make([]byte, %d-len(%s))...)\n", t.Size, expr)
+	}
+	return nil
+}
+
+func (m *method) encodePrimitive(expr string, kind reflect.Kind, order string) error {
+	switch kind {
+	case reflect.Bool:
+		m.printf("if %s {\nb = append(b, 1)\n} else {\nb = append(b, 0)\n}\n", expr)
+	case reflect.Int8, reflect.Uint8:
+		m.printf("b = append(b, byte(%s))\n", expr)
+	case reflect.Int16, reflect.Uint16, reflect.Int32, reflect.Uint32, reflect.Int64, reflect.Uint64:
+		n := bits(kind)
+		m.scratch = true
+		m.printf("%s.PutUint%d(s[:], uint%d(%s))\nb = append(b, s[:%d]...)\n", order, n, n, expr, n/8)
+	case reflect.Float32, reflect.Float64:
+		n := bits(kind)
+		m.scratch = true
+		m.imports["math"] = true
+		m.printf("%s.PutUint%d(s[:], math.Float%dbits(float%d(%s)))\nb = append(b, s[:%d]...)\n", order, n, n, n, expr, n/8)
+	default:
+		return m.unsupported("unsupported kind %s", kind)
+	}
+	return nil
+}
+
+/////////////////////////////////// Decoding ///////////////////////////////////
+
+func (m *method) decode(expr string, typ reflect.Type, t *encoding.Tag, order string) error {
+	order = m.fieldOrder(t, order)
+	kind := typ.Kind()
+
+	if t.Varint != encoding.NoVarint && isInteger(kind) {
+		return m.decodeVarint(expr, typ, t.Varint)
+	}
+
+	switch kind {
+	case reflect.Struct:
+		if !m.g.known(typ) {
+			return m.unsupported("no code generated for %s", typ)
+		}
+		m.printf("if err := %s.DecodeBinary(r, %s); err != nil {\nreturn err\n}\n", expr, order)
+	case reflect.Array:
+		return m.decodeElements(expr, typ, t, order)
+	case reflect.Slice:
+		return m.decodeSlice(expr, typ, t, order)
+	case reflect.String:
+		return m.decodeString(expr, typ, t, order)
+	default:
+		return m.decodePrimitive(expr, typ, order)
+	}
+	return nil
+}
+
+// readFull writes the reading of buf from r
+func (m *method) readFull(buf string) {
+	m.printf("if _, err := io.ReadFull(r, %s); err != nil {\nreturn err\n}\n", buf)
+}
+
+func (m *method) decodeVarint(expr string, typ reflect.Type, varint encoding.Varint) error {
+	name, err := m.typeName(typ)
+	if err != nil {
+		return err
+	}
+	kind, n := typ.Kind(), bits(typ.Kind())
+	x := m.local("x")
+	var cond []string
+	if varint == encoding.Uleb128 {
+		m.printf("%s, err := encoding.ReadUvarint(r)\n", x)
+		switch {
+		case isUnsigned(kind) && n < 64:
+			cond = append(cond, fmt.Sprintf("%s > math.MaxUint%d", x, n))
+		case !isUnsigned(kind):
+			cond = append(cond, fmt.Sprintf("%s > math.MaxInt%d", x, n))
+		}
+	} else {
+		m.printf("%s, err := encoding.ReadVarint(r)\n", x)
+		switch {
+		case isUnsigned(kind):
+			cond = append(cond, x+" < 0")
+			if n < 64 {
+				cond = append(cond, fmt.Sprintf("%s > math.MaxUint%d", x, n))
+			}
+		case n < 64:
+			cond = append(cond, fmt.Sprintf("%s < math.MinInt%d", x, n), fmt.Sprintf("%s > math.MaxInt%d", x, n))
+		}
+	}
+	m.printf("if err != nil {\nreturn err\n}\n")
+	if len(cond) > 0 {
+		m.imports["math"] = true
+		m.check("", strings.Join(cond, " || "), "ErrVarintOverflow", "%d does not fit in "+typ.String(), x)
+	}
+	m.printf("%s = %s(%s)\n", expr, name, x)
+	return nil
+}
+
+func (m *method) decodeElements(expr string, typ reflect.Type, t *encoding.Tag, order string) error {
+	if typ.Elem().Kind() == reflect.Uint8 && t.Varint == encoding.NoVarint {
+		if typ.Kind() == reflect.Array {
+			expr += "[:]"
+		}
+		m.readFull(expr)
+		return nil
+	}
+	i := m.local("i")
+	m.printf("for %s := range %s {\n", i, expr)
+	if err := m.decode(fmt.Sprintf("%s[%s]", expr, i), typ.Elem(), elemTag(t), order); err != nil {
+		return err
+	}
+	m.printf("}\n")
+	return nil
+}
+
+// decodeLength writes the decoding of the length of a slice or a string and
+// returns the local variable holding it
+func (m *method) decodeLength(t *encoding.Tag, order string) string {
+	n := m.local("n")
+	switch {
+	case t.Size > 0:
+		m.printf("%s := %d\n", n, t.Size)
+	case t.LenField != "":
+		m.printf("%s := int(v.%s)\n", n, t.LenField)
+	case t.LenVarint:
+		x := m.local("x")
+		m.printf("%s, err := encoding.ReadUvarint(r)\nif err != nil {\nreturn err\n}\n%s := int(%s)\n", x, n, x)
+	default:
+		kind := t.LenPrefix
+		if kind == reflect.Invalid {
+			kind = reflect.Int64
+		}
+		m.printf("var %s int\n", n)
+		m.decodePrimitive(n, reflect.TypeOf(0), order, kind)
+	}
+	return n
+}
+
+func (m *method) decodeSlice(expr string, typ reflect.Type, t *encoding.Tag, order string) error {
+	name, err := m.typeName(typ)
+	if err != nil {
+		return err
+	}
+	n := m.decodeLength(t, order)
+	m.imports["unsafe"] = true
+	size := fmt.Sprintf("int(unsafe.Sizeof(%s[0]))", expr)
+	m.checkLength(n, size)
+	if typ.Elem().Kind() == reflect.Uint8 && t.Varint == encoding.NoVarint {
+		buf := m.local("buf")
+		m.printf("%s, err := encoding.ReadBytes(r, %s)\nif err != nil {\nreturn err\n}\n", buf, n)
+		m.printf("%s = %s(%s)\n", expr, name, buf)
+		return nil
+	}
+	// the slice grows as elements are decoded so that a corrupted length
+	// cannot allocate more than the data available
+	elem, err := m.typeName(typ.Elem())
+	if err != nil {
+		return err
+	}
+	i, e := m.local("i"), m.local("e")
+	m.printf("%s = make(%s, 0, encoding.CapHint(%s, %s))\n", expr, name, n, size)
+	m.printf("for %s := 0; %s < %s; %s++ {\nvar %s %s\n", i, i, n, i, e, elem)
+	if err := m.decode(e, typ.Elem(), elemTag(t), order); err != nil {
+		return err
+	}
+	m.printf("%s = append(%s, %s)\n}\n", expr, expr, e)
+	return nil
+}
+
+// checkLength writes the checking of a decoded length against the limits
+// of the decoder
+func (m *method) checkLength(n string, elemSize string) {
+	m.printf("if err := encoding.CheckLength(r, %s, %s); err != nil {\nreturn err\n}\n", n, elemSize)
+}
+
+func (m *method) decodeString(expr string, typ reflect.Type, t *encoding.Tag, order string) error {
+	name, err := m.typeName(typ)
+	if err != nil {
+		return err
+	}
+	buf := m.local("buf")
+	if t.CString {
+		m.printf("%s, err := encoding.ReadTerminated(r)\nif err != nil {\nreturn err\n}\n", buf)
+	} else {
+		n := m.decodeLength(t, order)
+		m.checkLength(n, "1")
+		m.printf("%s, err := encoding.ReadBytes(r, %s)\nif err != nil {\nreturn err\n}\n", buf, n)
+		if t.Size > 0 {
+			// fixed size strings are NUL padded
+			m.imports["bytes"] = true
+			m.printf("if i := bytes.IndexByte(%s, 0); i >= 0 {\n%s = %s[:i]\n}\n", buf, buf, buf)
+		}
+	}
+	m.printf("%s = %s(%s)\n", expr, name, buf)
+	return nil
+}
+
+// decodePrimitive writes the decoding of a primitive of kind encoded (the
+// kind of typ by default) into expr
+func (m *method) decodePrimitive(expr string, typ reflect.Type, order string, encoded ...reflect.Kind) error {
+	name, err := m.typeName(typ)
+	if err != nil {
+		return err
+	}
+	kind := typ.Kind()
+	if len(encoded) > 0 {
+		kind = encoded[0]
+	}
+	n := bits(kind)
+	if n == 0 {
+		return m.unsupported("unsupported kind %s", kind)
+	}
+	m.scratch = true
+	m.readFull(fmt.Sprintf("s[:%d]", n/8))
+	value := fmt.Sprintf("%s.Uint%d(s[:])", order, n)
+	switch kind {
+	case reflect.Bool:
+		value = "s[0] != 0"
+	case reflect.Uint8:
+		value = "s[0]"
+	case reflect.Int8:
+		value = "int8(s[0])"
+	case reflect.Int16, reflect.Int32, reflect.Int64:
+		value = fmt.Sprintf("int%d(%s)", n, value)
+	case reflect.Float32, reflect.Float64:
+		m.imports["math"] = true
+		value = fmt.Sprintf("math.Float%dfrombits(%s)", n, value)
+	}
+	if name != kind.String() {
+		value = fmt.Sprintf("%s(%s)", name, value)
+	}
+	m.printf("%s = %s\n", expr, value)
+	return nil
+}
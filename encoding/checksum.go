@@ -0,0 +1,227 @@
+package encoding
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrBadMagic is matched by MagicError with errors.Is
+	ErrBadMagic = errors.New("Bad magic")
+	// ErrBadChecksum is matched by ChecksumError with errors.Is
+	ErrBadChecksum = errors.New("Bad checksum")
+)
+
+// MagicError is returned when a field does not hold the value given by its
+// magic option
+type MagicError struct {
+	Expected string
+	Got      string
+}
+
+// Error implements error interface
+func (e *MagicError) Error() string {
+	return fmt.Sprintf("%s: expecting %s, got %s", ErrBadMagic, e.Expected, e.Got)
+}
+
+// Is returns true if target is ErrBadMagic
+func (e *MagicError) Is(target error) bool {
+	return target == ErrBadMagic
+}
+
+// ChecksumError is returned when a decoded checksum does not match the one
+// computed over the decoded data
+type ChecksumError struct {
+	Stored   uint32
+	Computed uint32
+}
+
+// Error implements error interface
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("%s: stored %#08x, computed %#08x", ErrBadChecksum, e.Stored, e.Computed)
+}
+
+// Is returns true if target is ErrBadChecksum
+func (e *ChecksumError) Is(target error) bool {
+	return target == ErrBadChecksum
+}
+
+// parseMagic parses the magic option of a field of type typ
+func parseMagic(typ reflect.Type, s string) (reflect.Value, error) {
+	magic := reflect.New(typ).Elem()
+	switch kind := typ.Kind(); {
+	case isUnsigned(kind):
+		x, err := strconv.ParseUint(s, 0, primitiveSize(kind)*8)
+		if err != nil {
+			return magic, fmt.Errorf("bad magic %q for %s", s, typ)
+		}
+		magic.SetUint(x)
+	case isInteger(kind):
+		x, err := strconv.ParseInt(s, 0, primitiveSize(kind)*8)
+		if err != nil {
+			return magic, fmt.Errorf("bad magic %q for %s", s, typ)
+		}
+		magic.SetInt(x)
+	case kind == reflect.Array && typ.Elem().Kind() == reflect.Uint8:
+		b := []byte(s)
+		if strings.HasPrefix(s, "0x") {
+			var err error
+			if b, err = hex.DecodeString(s[2:]); err != nil {
+				return magic, fmt.Errorf("bad magic %q", s)
+			}
+		}
+		if len(b) != typ.Len() {
+			return magic, fmt.Errorf("magic %q is not %d bytes long", s, typ.Len())
+		}
+		for i := range b {
+			magic.Index(i).SetUint(uint64(b[i]))
+		}
+	default:
+		return magic, fmt.Errorf("magic option only applies to integers and byte arrays")
+	}
+	return magic, nil
+}
+
+// magicString formats a value checked against a magic
+func magicString(v reflect.Value) string {
+	switch {
+	case isUnsigned(v.Kind()):
+		return fmt.Sprintf("%#x", v.Uint())
+	case isInteger(v.Kind()):
+		return fmt.Sprintf("%#x", v.Int())
+	}
+	b := make([]byte, v.Len())
+	for i := range b {
+		b[i] = byte(v.Index(i).Uint())
+	}
+	return fmt.Sprintf("%q", b)
+}
+
+// checkMagic returns a MagicError if v does not hold the magic of f
+func checkMagic(f *field, v reflect.Value) error {
+	if got, expected := magicString(v), magicString(f.magic); got != expected {
+		return &MagicError{expected, got}
+	}
+	return nil
+}
+
+// checksum is a CRC-32 of a range of fields of a struct
+type checksum struct {
+	// positions in structSpec.fields of the field holding the checksum and
+	// of the first and last fields covered
+	field, start, end int
+}
+
+// newChecksum creates the checksum held by field f of spec
+func newChecksum(spec *structSpec, positions map[string]int, f *field) (c checksum, err error) {
+	var ok bool
+	c.field = positions[f.name]
+	if c.start, ok = positions[f.tag.CRC32Start]; !ok {
+		return c, fmt.Errorf("unknown crc32 start field %s", f.tag.CRC32Start)
+	}
+	if c.end, ok = positions[f.tag.CRC32End]; !ok {
+		return c, fmt.Errorf("unknown crc32 end field %s", f.tag.CRC32End)
+	}
+	switch {
+	case c.start > c.end:
+		return c, fmt.Errorf("crc32 range %s:%s is reversed", f.tag.CRC32Start, f.tag.CRC32End)
+	case c.field >= c.start && c.field <= c.end:
+		return c, fmt.Errorf("crc32 range %s:%s includes the checksum", f.tag.CRC32Start, f.tag.CRC32End)
+	}
+	// skipped bytes are not read by decoders
+	for _, rf := range spec.fields[c.start+1 : c.end+1] {
+		if rf.tag.Offset >= 0 || rf.offIndex >= 0 {
+			return c, fmt.Errorf("offset option of %s is not allowed within crc32 range", rf.name)
+		}
+	}
+	return c, nil
+}
+
+// encodedSum is the state of a checksum while encoding a struct
+type encodedSum struct {
+	// start of the range and position of the checksum in the buffer
+	start, pos int
+	sum        uint32
+	done       bool
+}
+
+// sumFields updates the checksums of spec once field i is encoded, pos being
+// the position of the field in the buffer. Checksums are written as soon as
+// both the field holding them and their range are encoded.
+func (e *encoder) sumFields(spec *structSpec, sums []encodedSum, i, pos int, order Endianness) {
+	for j, c := range spec.checksums {
+		s := &sums[j]
+		switch i {
+		case c.field:
+			s.pos = pos
+		case c.end:
+			s.sum, s.done = crc32.ChecksumIEEE(e.buf[s.start:]), true
+		default:
+			continue
+		}
+		if s.done && s.pos >= 0 {
+			fieldOrder(order, &spec.fields[c.field].tag).PutUint32(e.buf[s.pos:], s.sum)
+		}
+	}
+}
+
+// decodedSum is the state of a checksum while decoding a struct
+type decodedSum struct {
+	h hash.Hash32
+	// stored checksum and offset of the field holding it
+	stored uint32
+	offset int64
+	// whether the stored checksum is decoded and the range is hashed
+	decoded, done bool
+}
+
+// startSums starts hashing the data of the checksums whose range starts with
+// field i of spec
+func (d *decoder) startSums(spec *structSpec, sums []decodedSum, i int) {
+	for j, c := range spec.checksums {
+		if c.start == i {
+			sums[j].h = crc32.NewIEEE()
+			d.sums = append(d.sums, sums[j].h)
+		}
+	}
+}
+
+// sumFields updates the checksums of spec once field i of struct v is
+// decoded, fieldStart being its offset, and verifies them as soon as both
+// the field holding them and their range are decoded
+func (d *decoder) sumFields(v reflect.Value, spec *structSpec, sums []decodedSum, i int, fieldStart int64) error {
+	for j, c := range spec.checksums {
+		s := &sums[j]
+		switch i {
+		case c.field:
+			s.stored, s.offset, s.decoded = uint32(v.Field(spec.fields[i].index).Uint()), fieldStart, true
+		case c.end:
+			d.stopSum(s.h)
+			s.done = true
+		default:
+			continue
+		}
+		if s.decoded && s.done {
+			if computed := s.h.Sum32(); computed != s.stored {
+				return d.fieldError(&ChecksumError{s.stored, computed}, spec.fields[c.field].name, s.offset)
+			}
+		}
+	}
+	return nil
+}
+
+// stopSum stops hashing decoded data into h
+func (d *decoder) stopSum(h hash.Hash32) {
+	for i := range d.sums {
+		if d.sums[i] == h {
+			d.sums = append(d.sums[:i], d.sums[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Command bindump prints an annotated hexdump of binary records described by
+// a layout (c.f. encoding.ParseLayout), like:
+//
+//	bindump -layout 'Magic [4]byte; Count uint16; Values []uint32 len=Count' file.bin
+//
+// Records are decoded one after the other until the end of the input, which
+// is read from stdin when no file is given. Decoding is bounded by
+// encoding.DefaultLimits unless overridden, a zero limit meaning no limit.
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+
+	"github.com/0xrawsec/golang-utils/encoding"
+)
+
+func main() {
+	var (
+		layout    string
+		bigEndian bool
+		offset    int64
+		count     int
+		limits    = encoding.DefaultLimits
+	)
+
+	flag.StringVar(&layout, "layout", "", "Layout of the records")
+	flag.BoolVar(&bigEndian, "be", false, "Decode big endian records")
+	flag.Int64Var(&offset, "offset", 0, "Offset of the first record")
+	flag.IntVar(&count, "n", 0, "Number of records to dump (0 until the end of input)")
+	flag.IntVar(&limits.MaxSliceLen, "maxlen", limits.MaxSliceLen, "Maximum number of elements of slices and strings (0 for no limit)")
+	flag.Int64Var(&limits.MaxAlloc, "maxalloc", limits.MaxAlloc, "Maximum number of bytes allocated per record (0 for no limit)")
+	flag.IntVar(&limits.MaxDepth, "maxdepth", limits.MaxDepth, "Maximum nesting depth of records (0 for no limit)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s -layout LAYOUT [OPTIONS] [FILE]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if layout == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := dump(layout, flag.Arg(0), bigEndian, offset, count, limits); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func dump(layout, path string, bigEndian bool, offset int64, count int, limits encoding.Limits) error {
+	typ, err := encoding.ParseLayout(layout)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if path != "" {
+		fd, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer fd.Close()
+		r = fd
+	}
+	var order encoding.Endianness = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	dec := encoding.NewDecoder(bufio.NewReader(r), order)
+	dec.SetLimits(limits)
+	// dumped offsets are the ones in the input
+	if err := dec.Skip(offset); err != nil {
+		return err
+	}
+	for i := 0; count == 0 || i < count; i++ {
+		// io.EOF is only returned at the end of a record
+		if err := dec.Dump(w, reflect.New(typ).Interface()); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
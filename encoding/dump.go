@@ -0,0 +1,273 @@
+package encoding
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// dumpWidth is the number of bytes per line of hexdumps
+	dumpWidth = 16
+	// dumpMaxBytes is the number of bytes of byte slices shown in values
+	dumpMaxBytes = 32
+)
+
+var (
+	// ErrInvalidLayout is returned when parsing an invalid layout
+	ErrInvalidLayout = errors.New("Invalid layout")
+
+	layoutTypes = map[string]reflect.Type{
+		"bool":    reflect.TypeOf(false),
+		"byte":    reflect.TypeOf(byte(0)),
+		"int8":    reflect.TypeOf(int8(0)),
+		"int16":   reflect.TypeOf(int16(0)),
+		"int32":   reflect.TypeOf(int32(0)),
+		"int64":   reflect.TypeOf(int64(0)),
+		"uint8":   reflect.TypeOf(uint8(0)),
+		"uint16":  reflect.TypeOf(uint16(0)),
+		"uint32":  reflect.TypeOf(uint32(0)),
+		"uint64":  reflect.TypeOf(uint64(0)),
+		"float32": reflect.TypeOf(float32(0)),
+		"float64": reflect.TypeOf(float64(0)),
+		"string":  reflect.TypeOf(""),
+	}
+)
+
+// TraceFunc is called by a Decoder for every value decoded, field being the
+// path of the value, offset its offset in the stream and raw the bytes it is
+// decoded from. Lengths, pointer presence bytes and interface type ids are
+// reported with the path of their value followed by " (length)",
+// " (present)" and " (type)". raw is only valid during the call.
+type TraceFunc func(field string, offset int64, raw []byte, value reflect.Value)
+
+// SetTrace sets the function called for every value decoded, nil disabling
+// tracing. Unmarshaler implementations are not used while tracing so that
+// the fields of structures are reported.
+func (dec *Decoder) SetTrace(fn TraceFunc) {
+	dec.d.trace = fn
+}
+
+// Dump decodes the next value from the stream into the value pointed by data
+// like Decode and writes an annotated hexdump of the decoded bytes to w: the
+// offset, the raw bytes, the field path and the decoded value of every
+// field. Bytes read before a decoding error are dumped as undecoded.
+func (dec *Decoder) Dump(w io.Writer, data interface{}) error {
+	var werr error
+	write := func(field string, offset int64, raw []byte, value string) {
+		if werr == nil {
+			werr = writeDump(w, field, offset, raw, value)
+		}
+	}
+
+	prev := dec.d.trace
+	defer dec.SetTrace(prev)
+	dec.SetTrace(func(field string, offset int64, raw []byte, v reflect.Value) {
+		write(field, offset, raw, formatValue(v))
+	})
+
+	err := dec.Decode(data)
+	if len(dec.d.traced) > 0 {
+		write("(undecoded)", dec.d.offset-int64(len(dec.d.traced)), dec.d.traced, "")
+		dec.d.traced = dec.d.traced[:0]
+	}
+	if err != nil {
+		return err
+	}
+	return werr
+}
+
+// Dump decodes a value from r into the value pointed by data and writes an
+// annotated hexdump of the decoded bytes to w (c.f. Decoder.Dump)
+func Dump(w io.Writer, r io.Reader, data interface{}, endianness Endianness) error {
+	return NewDecoder(r, endianness).Dump(w, data)
+}
+
+// writeDump writes the hexdump lines of a value, raw bytes not fitting on
+// the first line being continued on the following ones
+func writeDump(w io.Writer, field string, offset int64, raw []byte, value string) error {
+	for first := true; first || len(raw) > 0; first = false {
+		n := len(raw)
+		if n > dumpWidth {
+			n = dumpWidth
+		}
+		hexa := make([]string, n)
+		for i, b := range raw[:n] {
+			hexa[i] = hex.EncodeToString([]byte{b})
+		}
+		line := fmt.Sprintf("%08x  %-*s", offset, dumpWidth*3-1, strings.Join(hexa, " "))
+		if first {
+			line += "  " + field
+			if value != "" {
+				line += " = " + value
+			}
+		}
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line, " ")); err != nil {
+			return err
+		}
+		raw = raw[n:]
+		offset += int64(n)
+	}
+	return nil
+}
+
+// formatValue formats a decoded value for dumps
+func formatValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	if v.CanInterface() {
+		if s, ok := v.Interface().(fmt.Stringer); ok && v.Kind() != reflect.String {
+			return s.String()
+		}
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprintf("%d (%#x)", v.Int(), v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprintf("%d (%#x)", v.Uint(), v.Uint())
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Slice, reflect.Array:
+		n := v.Len()
+		b := make([]byte, 0, dumpMaxBytes)
+		for i := 0; i < n && i < dumpMaxBytes; i++ {
+			b = append(b, byte(v.Index(i).Uint()))
+		}
+		if n > dumpMaxBytes {
+			return fmt.Sprintf("%q... (%d bytes)", b, n)
+		}
+		return fmt.Sprintf("%q", b)
+	}
+	if v.CanInterface() {
+		return fmt.Sprint(v.Interface())
+	}
+	return v.String()
+}
+
+// push appends elem to the path of the value being decoded while tracing
+func (d *decoder) push(elem string) {
+	if d.trace != nil {
+		d.path = append(d.path, elem)
+	}
+}
+
+// pop removes the last element of the path of the value being decoded
+func (d *decoder) pop() {
+	if d.trace != nil {
+		d.path = d.path[:len(d.path)-1]
+	}
+}
+
+// tracePath returns the path of the value being decoded followed by elems
+func (d *decoder) tracePath(elems ...string) string {
+	path := ""
+	all := append(d.path[:len(d.path):len(d.path)], elems...)
+	for i := len(all) - 1; i >= 0; i-- {
+		path = joinPath(all[i], path)
+	}
+	return path
+}
+
+// emit traces v, the bytes read since the last trace being the ones it is
+// decoded from
+func (d *decoder) emit(suffix string, v reflect.Value) {
+	if d.trace != nil {
+		d.traceAt(d.tracePath()+suffix, d.offset-int64(len(d.traced)), v)
+	}
+}
+
+// traceAt traces v at offset with the bytes read since the last trace
+func (d *decoder) traceAt(field string, offset int64, v reflect.Value) {
+	if d.trace != nil {
+		d.trace(field, offset, d.traced, v)
+		d.traced = d.traced[:0]
+	}
+}
+
+// ParseLayout parses a structure layout into a structure type which can be
+// decoded or dumped without defining the structure in Go. The layout is a
+// list of fields separated by semicolons or newlines, each field being made
+// of a name, a type and optional bin tag options, like:
+//
+//	Magic [4]byte; Count uint16; Entries []uint32 len=Count
+//
+// Types are bool, byte, string, sized integers and floats, arrays [N]T and
+// slices []T of those.
+func ParseLayout(layout string) (reflect.Type, error) {
+	fields := make([]reflect.StructField, 0)
+	names := make(map[string]bool)
+	for _, decl := range strings.FieldsFunc(layout, func(r rune) bool { return r == ';' || r == '\n' }) {
+		parts := strings.Fields(decl)
+		if len(parts) == 0 {
+			continue
+		}
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("%w: expecting name, type and options in %q", ErrInvalidLayout, strings.TrimSpace(decl))
+		}
+		name := parts[0]
+		if !token.IsIdentifier(name) || !token.IsExported(name) {
+			return nil, fmt.Errorf("%w: field name %q must be an exported identifier", ErrInvalidLayout, name)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("%w: duplicate field %s", ErrInvalidLayout, name)
+		}
+		names[name] = true
+		typ, err := parseLayoutType(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		f := reflect.StructField{Name: name, Type: typ}
+		if len(parts) == 3 {
+			f.Tag = reflect.StructTag(fmt.Sprintf("%s:%q", TagName, parts[2]))
+		}
+		fields = append(fields, f)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: no field", ErrInvalidLayout)
+	}
+	typ := reflect.StructOf(fields)
+	if _, err := getStructSpec(typ); err != nil {
+		return nil, err
+	}
+	return typ, nil
+}
+
+func parseLayoutType(s string) (reflect.Type, error) {
+	switch {
+	case strings.HasPrefix(s, "[]"):
+		elem, err := parseLayoutType(s[2:])
+		if err != nil {
+			return nil, err
+		}
+		return reflect.SliceOf(elem), nil
+	case strings.HasPrefix(s, "["):
+		end := strings.Index(s, "]")
+		if end < 0 {
+			return nil, fmt.Errorf("%w: type %q", ErrInvalidLayout, s)
+		}
+		n, err := strconv.Atoi(s[1:end])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%w: array length in %q", ErrInvalidLayout, s)
+		}
+		elem, err := parseLayoutType(s[end+1:])
+		if err != nil {
+			return nil, err
+		}
+		// reflect.ArrayOf panics on overflow and decoding huge arrays takes
+		// forever, so arrays are bounded like decoded slices
+		if n > DefaultLimits.MaxSliceLen || uint64(n)*uint64(elem.Size()) > uint64(DefaultLimits.MaxAlloc) {
+			return nil, fmt.Errorf("%w: array %q is too large", ErrInvalidLayout, s)
+		}
+		return reflect.ArrayOf(n, elem), nil
+	}
+	if typ, ok := layoutTypes[s]; ok {
+		return typ, nil
+	}
+	return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidLayout, s)
+}
@@ -1,12 +1,11 @@
 package encoding
 
 import (
-	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
-	"os"
+	"math"
 	"reflect"
 )
 
@@ -22,8 +21,40 @@ var (
 	ErrInvalidNilPointer = errors.New("Nil pointer is invalid")
 	// No Pointer interface
 	ErrNoPointerInterface = errors.New("Interface expect to be a pointer")
+	// ErrLengthMismatch is returned when a length field does not match the
+	// length of the slice or string it refers to
+	ErrLengthMismatch = errors.New("Length mismatch")
+	// ErrLengthOverflow is returned when a length cannot be encoded
+	ErrLengthOverflow = errors.New("Length overflow")
+	// ErrVarintOverflow is returned when a variable length integer does not
+	// fit in its destination
+	ErrVarintOverflow = errors.New("Varint overflow")
+	// ErrNulInString is returned when encoding a NUL terminated string
+	// containing a NUL character
+	ErrNulInString = errors.New("NUL character in string")
+	// ErrOffsetOverlap is returned when encoding a field at an offset already
+	// used by previous fields
+	ErrOffsetOverlap = errors.New("Offset overlaps previous data")
+	// ErrInvalidPresence is returned when decoding a pointer presence byte
+	// which is neither 0 nor 1
+	ErrInvalidPresence = errors.New("Invalid pointer presence byte")
+	// ErrBitsOverflow is returned when a value does not fit in its bit-field
+	ErrBitsOverflow = errors.New("Bit-field overflow")
 )
 
+// Marshaler is implemented by types able to encode themselves without
+// reflection, like the ones whose methods are generated by the bingen
+// package. EncodeBinary appends the encoding of the value to b.
+type Marshaler interface {
+	EncodeBinary(b []byte, order Endianness) ([]byte, error)
+}
+
+// Unmarshaler is the counterpart of Marshaler. DecodeBinary decodes the
+// value from r.
+type Unmarshaler interface {
+	DecodeBinary(r io.Reader, order Endianness) error
+}
+
 // Unpack data type from reader object. An optional offset can be specified.
 func Unpack(reader io.ReadSeeker, endianness Endianness, data interface{}, offsets ...int64) error {
 
@@ -35,17 +66,15 @@ func Unpack(reader io.ReadSeeker, endianness Endianness, data interface{}, offse
 		}
 		// An offset to deal with
 	case len(offsets) == 1:
-		if soughtOffset, err := reader.Seek(offsets[0], os.SEEK_SET); soughtOffset != offsets[0] || err != nil {
-			switch {
-			case err != nil:
-				return err
-			case soughtOffset != offsets[0]:
-				return ErrSeeking
-			default:
-				if err := binary.Read(reader, endianness, data); err != nil {
-					return err
-				}
-			}
+		soughtOffset, err := reader.Seek(offsets[0], io.SeekStart)
+		switch {
+		case err != nil:
+			return err
+		case soughtOffset != offsets[0]:
+			return ErrSeeking
+		}
+		if err := binary.Read(reader, endianness, data); err != nil {
+			return err
 		}
 		// Error if more than one offset
 	default:
@@ -54,239 +83,50 @@ func Unpack(reader io.ReadSeeker, endianness Endianness, data interface{}, offse
 	return nil
 }
 
-func marshalArray(data interface{}, endianness Endianness) ([]byte, error) {
-	var out []byte
-	val := reflect.ValueOf(data)
-	if val.IsNil() {
-		return out, ErrInvalidNilPointer
-	}
-	elem := val.Elem()
-	if elem.Kind() != reflect.Array {
-		return out, fmt.Errorf("Not an Array structure")
-	}
-	for k := 0; k < elem.Len(); k++ {
-		buff, err := Marshal(elem.Index(k).Addr().Interface(), endianness)
-		if err != nil {
-			return out, err
-		}
-		out = append(out, buff...)
-	}
-	return out, nil
-}
-
-func marshalSlice(data interface{}, endianness Endianness) ([]byte, error) {
-	var out []byte
-	val := reflect.ValueOf(data)
-	if val.IsNil() {
-		return out, ErrInvalidNilPointer
-	}
-	elem := val.Elem()
-	if elem.Kind() != reflect.Slice {
-		return out, fmt.Errorf("Not a Slice object")
-	}
-	s := elem
-	// We first serialize slice length as a int64
-	sliceLen := int64(s.Len())
-	buff, err := Marshal(&sliceLen, endianness)
-	if err != nil {
-		return out, err
-	}
-	out = append(out, buff...)
-	for k := 0; k < s.Len(); k++ {
-		buff, err := Marshal(s.Index(k).Addr().Interface(), endianness)
-		if err != nil {
-			return out, err
-		}
-		out = append(out, buff...)
-	}
-	return out, nil
-}
-
+// Marshal encodes the value pointed by data. Structures are encoded field by
+// field following the options of their bin tags (c.f. TagName), arrays
+// element by element and slices are prefixed by their length. Maps are
+// encoded as their length followed by their entries sorted by encoded keys,
+// pointers as a presence byte (0 for nil) followed by the pointed value and
+// interfaces as the id of their concrete type (c.f. RegisterType) followed by
+// the value. Structures implementing Marshaler encode themselves.
 func Marshal(data interface{}, endianness Endianness) ([]byte, error) {
-	var out []byte
-	val := reflect.ValueOf(data)
-	if val.Kind() != reflect.Ptr {
-		return out, ErrNoPointerInterface
-	}
-	if val.IsNil() {
-		return out, ErrInvalidNilPointer
+	val, err := pointerElem(data)
+	if err != nil {
+		return nil, err
 	}
-	elem := val.Elem()
-	typ := elem.Type()
-	switch typ.Kind() {
-	case reflect.Struct:
-		for i := 0; i < typ.NumField(); i++ {
-			tField := typ.Field(i)
-			// Unmarshal recursively if field of struct is a struct
-			switch tField.Type.Kind() {
-			case reflect.Struct:
-				buff, err := Marshal(elem.Field(i).Addr().Interface(), endianness)
-				if err != nil {
-					return out, err
-				}
-				out = append(out, buff...)
-			case reflect.Array:
-				buff, err := marshalArray(elem.Field(i).Addr().Interface(), endianness)
-				if err != nil {
-					return out, err
-				}
-				out = append(out, buff...)
-			case reflect.Slice:
-				buff, err := marshalSlice(elem.Field(i).Addr().Interface(), endianness)
-				if err != nil {
-					return out, err
-				}
-				out = append(out, buff...)
-			default:
-				buff, err := Marshal(elem.Field(i).Addr().Interface(), endianness)
-				if err != nil {
-					return out, err
-				}
-				out = append(out, buff...)
-			}
-		}
-	case reflect.Array:
-		buff, err := marshalArray(elem.Addr().Interface(), endianness)
-		if err != nil {
-			return out, err
-		}
-		out = append(out, buff...)
-
-	case reflect.Slice:
-		buff, err := marshalSlice(elem.Addr().Interface(), endianness)
-		if err != nil {
-			return out, err
-		}
-		out = append(out, buff...)
-
-	default:
-		writter := new(bytes.Buffer)
-		if err := binary.Write(writter, endianness, elem.Interface()); err != nil {
-			return out, err
-		}
-		out = append(out, writter.Bytes()...)
+	e := newEncoder()
+	if err := e.encode(val, endianness, &Tag{}); err != nil {
+		return nil, err
 	}
-	return out, nil
+	return e.buf, nil
 }
 
+// UnmarshaInitSlice decodes the elements of an already initialized slice,
+// the length of the slice not being decoded from reader
 func UnmarshaInitSlice(reader io.Reader, data interface{}, endianness Endianness) error {
-	val := reflect.ValueOf(data)
-	if val.IsNil() {
-		return ErrInvalidNilPointer
+	slice, err := pointerElem(data)
+	if err != nil {
+		return err
 	}
-	slice := val.Elem()
 	if slice.Kind() != reflect.Slice {
 		return fmt.Errorf("Not a slice object")
 	}
 	if slice.Len() == 0 {
 		return fmt.Errorf("Not initialized slice")
 	}
-	for k := 0; k < slice.Len(); k++ {
-		err := Unmarshal(reader, slice.Index(k).Addr().Interface(), endianness)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func unmarshalArray(reader io.Reader, data interface{}, endianness Endianness) error {
-	val := reflect.ValueOf(data)
-	if val.IsNil() {
-		return ErrInvalidNilPointer
-	}
-	array := val.Elem()
-	if array.Kind() != reflect.Array {
-		return fmt.Errorf("Not an Array structure")
-	}
-	for k := 0; k < array.Len(); k++ {
-		err := Unmarshal(reader, array.Index(k).Addr().Interface(), endianness)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func unmarshalSlice(reader io.Reader, data interface{}, endianness Endianness) error {
-	var sliceLen int64
-	val := reflect.ValueOf(data)
-	if val.IsNil() {
-		return ErrInvalidNilPointer
-	}
-	elem := val.Elem()
-	if elem.Kind() != reflect.Slice {
-		return fmt.Errorf("Not a Slice object")
-	}
-	err := Unmarshal(reader, &sliceLen, endianness)
-	if err != nil {
-		return err
-	}
-	s := elem
-	newS := reflect.MakeSlice(s.Type(), int(sliceLen), int(sliceLen))
-	s.Set(newS)
-	//return UnmarshaInitSlice(reader, newS.Interface(), endianness)
-
-	for k := 0; k < s.Len(); k++ {
-		err := Unmarshal(reader, s.Index(k).Addr().Interface(), endianness)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return newDecoder(reader).decodeElements(slice, endianness, &Tag{}, 0)
 }
 
+// Unmarshal decodes data from reader into the value pointed by data, the
+// counterpart of Marshal. Structures implementing Unmarshaler decode
+// themselves.
 func Unmarshal(reader io.Reader, data interface{}, endianness Endianness) error {
-	val := reflect.ValueOf(data)
-	if val.IsNil() {
-		return ErrInvalidNilPointer
-	}
-	elem := val.Elem()
-	typ := elem.Type()
-	switch typ.Kind() {
-	case reflect.Struct:
-		for i := 0; i < typ.NumField(); i++ {
-			tField := typ.Field(i)
-			// Unmarshal recursively if field of struct is a struct
-			switch tField.Type.Kind() {
-			case reflect.Struct:
-				err := Unmarshal(reader, elem.Field(i).Addr().Interface(), endianness)
-				if err != nil {
-					return err
-				}
-			case reflect.Array:
-				err := unmarshalArray(reader, elem.Field(i).Addr().Interface(), endianness)
-				if err != nil {
-					return err
-				}
-			case reflect.Slice:
-				err := unmarshalSlice(reader, elem.Field(i).Addr().Interface(), endianness)
-				if err != nil {
-					return err
-				}
-			default:
-				if err := Unmarshal(reader, elem.Field(i).Addr().Interface(), endianness); err != nil {
-					return err
-				}
-			}
-		}
-
-	case reflect.Array:
-		err := unmarshalArray(reader, elem.Addr().Interface(), endianness)
-		if err != nil {
-			return err
-		}
-
-	case reflect.Slice:
-		err := unmarshalSlice(reader, elem.Addr().Interface(), endianness)
-		if err != nil {
-			return err
-		}
+	return NewDecoder(reader, endianness).Decode(data)
+}
 
-	default:
-		if err := binary.Read(reader, endianness, data); err != nil {
-			return err
-		}
-	}
-	return nil
+// UnmarshalAt decodes data from reader at offset off. As the reader is
+// seekable, bin tag offsets can point anywhere in the structure.
+func UnmarshalAt(reader io.ReaderAt, off int64, data interface{}, endianness Endianness) error {
+	return Unmarshal(io.NewSectionReader(reader, off, math.MaxInt64-off), data, endianness)
 }
@@ -0,0 +1,158 @@
+package encoding
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	// allocChunk is the maximum number of bytes allocated at once for
+	// decoded slices and strings, bigger ones growing as data is read so that
+	// a corrupted length cannot allocate more than the data available
+	allocChunk = 1 << 16
+)
+
+var (
+	// ErrLimitExceeded is matched by LimitError with errors.Is
+	ErrLimitExceeded = errors.New("Decoding limit exceeded")
+	// ErrNegativeLength is returned when decoding a negative length
+	ErrNegativeLength = errors.New("Negative length")
+
+	// DefaultLimits are reasonable limits to decode untrusted data
+	DefaultLimits = Limits{
+		MaxSliceLen: 1 << 24,
+		MaxAlloc:    1 << 30,
+		MaxDepth:    64,
+	}
+)
+
+// Limits protect the decoding of untrusted data, a zero limit meaning no
+// limit. Allocation and depth are accounted per decoded value.
+type Limits struct {
+	// MaxSliceLen is the maximum number of elements of slices and of
+	// units of strings. Slices of zero sized elements, which consume no
+	// data, are bounded by DefaultLimits when it is zero.
+	MaxSliceLen int
+	// MaxAlloc is the maximum number of bytes allocated for slices and
+	// strings
+	MaxAlloc int64
+	// MaxDepth is the maximum nesting of structures, arrays and slices
+	MaxDepth int
+}
+
+// LimitError is returned when decoded data exceeds one of the Limits
+type LimitError struct {
+	// Limit is the name of the exceeded limit
+	Limit string
+	Value int64
+	Max   int64
+}
+
+// Error implements error interface
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: %s %d > %d", ErrLimitExceeded, e.Limit, e.Value, e.Max)
+}
+
+// Is returns true if target is ErrLimitExceeded
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// enter is called when decoding a nested value
+func (d *decoder) enter() error {
+	d.depth++
+	if max := d.limits.MaxDepth; max > 0 && d.depth > max {
+		return &LimitError{"MaxDepth", int64(d.depth), int64(max)}
+	}
+	return nil
+}
+
+// leave is called once a nested value is decoded
+func (d *decoder) leave() {
+	d.depth--
+}
+
+// checkLength checks a decoded length of n elements of elemSize bytes
+// against the limits and accounts the allocation
+func (d *decoder) checkLength(n, elemSize int) error {
+	if n < 0 {
+		return fmt.Errorf("%w: %d", ErrNegativeLength, n)
+	}
+	max := d.limits.MaxSliceLen
+	if max == 0 && elemSize == 0 {
+		// the length is the only bound of the decoding time
+		max = DefaultLimits.MaxSliceLen
+	}
+	if max > 0 && n > max {
+		return &LimitError{"MaxSliceLen", int64(n), int64(max)}
+	}
+	size := int64(n) * int64(elemSize)
+	if elemSize != 0 && size/int64(elemSize) != int64(n) {
+		size = math.MaxInt64
+	}
+	if max := d.limits.MaxAlloc; max > 0 {
+		if size > max-d.allocated {
+			return &LimitError{"MaxAlloc", d.allocated + size, max}
+		}
+		d.allocated += size
+	}
+	return nil
+}
+
+// readBytes reads n bytes, n being checked by the caller
+func (d *decoder) readBytes(n int) ([]byte, error) {
+	if n <= allocChunk {
+		b := make([]byte, n)
+		return b, d.read(b)
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, allocChunk))
+	if _, err := io.CopyN(buf, d, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// asDecoder returns the decoder behind r, creating one without limits if r
+// is not a decoder
+func asDecoder(r io.Reader) *decoder {
+	if d, ok := r.(*decoder); ok {
+		return d
+	}
+	return newDecoder(r)
+}
+
+// CheckLength checks a decoded length of n elements of elemSize bytes. It is
+// meant to be used by Unmarshaler implementations, r being the reader
+// given to DecodeBinary, so that the limits of the Decoder apply.
+func CheckLength(r io.Reader, n, elemSize int) error {
+	return asDecoder(r).checkLength(n, elemSize)
+}
+
+// ReadBytes reads n bytes from r without trusting n for allocation: big
+// buffers grow as data is read. n is expected to be checked with
+// CheckLength.
+func ReadBytes(r io.Reader, n int) ([]byte, error) {
+	return asDecoder(r).readBytes(n)
+}
+
+// ReadTerminated reads bytes from r until a NUL byte, which is consumed but
+// not returned. The length of the data is checked against the limits of the
+// Decoder r is (c.f. CheckLength) as it is read.
+func ReadTerminated(r io.Reader) ([]byte, error) {
+	return asDecoder(r).readTerminated(1)
+}
+
+// CapHint returns the capacity to use to allocate a slice of n elements of
+// elemSize bytes, n being an untrusted decoded length
+func CapHint(n, elemSize int) int {
+	if elemSize > 0 && n > allocChunk/elemSize {
+		return allocChunk / elemSize
+	}
+	return n
+}
@@ -0,0 +1,539 @@
+package encoding
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// UnsupportedTypeError is returned when trying to encode or decode a type
+// which has no binary representation
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+// Error implements error interface
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("Unsupported type %s", e.Type)
+}
+
+// encoder encodes values by appending them to a buffer
+type encoder struct {
+	buf []byte
+	// base is the offset of buf in the output stream
+	base    int64
+	scratch [16]byte
+	// pointers being encoded, used to detect cycles
+	pointers map[uintptr]bool
+}
+
+func newEncoder() *encoder {
+	return &encoder{}
+}
+
+// encode encodes v, t being the tag of the field v comes from
+func (e *encoder) encode(v reflect.Value, order Endianness, t *Tag) error {
+	if t.Order != nil {
+		order = t.Order
+	}
+
+	if t.Varint != NoVarint && isInteger(v.Kind()) {
+		return e.encodeVarint(v, t.Varint)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if m, ok := addrInterface(v).(Marshaler); ok {
+			var err error
+			e.buf, err = m.EncodeBinary(e.buf, order)
+			return err
+		}
+		return e.encodeStruct(v, order)
+	case reflect.Array:
+		return e.encodeElements(v, order, t)
+	case reflect.Slice:
+		return e.encodeSlice(v, order, t)
+	case reflect.String:
+		return e.encodeString(v, order, t)
+	case reflect.Map:
+		return e.encodeMap(v, order, t)
+	case reflect.Ptr:
+		return e.encodePointer(v, order, t)
+	case reflect.Interface:
+		return e.encodeInterface(v, order, t)
+	default:
+		return e.encodePrimitive(v, order)
+	}
+}
+
+func (e *encoder) encodeStruct(v reflect.Value, order Endianness) error {
+	spec, err := getStructSpec(v.Type())
+	if err != nil {
+		return err
+	}
+	start := len(e.buf)
+	sums := make([]encodedSum, len(spec.checksums))
+	for j := range sums {
+		sums[j].pos = -1
+	}
+	for i := range spec.fields {
+		f := &spec.fields[i]
+		fv := v.Field(f.index)
+		if off := f.offset(v); off >= 0 {
+			pad := int64(start) + off - int64(len(e.buf))
+			if pad < 0 {
+				return fmt.Errorf("%w: %s at offset %d", ErrOffsetOverlap, f.name, off)
+			}
+			e.buf = append(e.buf, make([]byte, pad)...)
+		}
+		if f.lenIndex >= 0 {
+			// length is not encoded but must be consistent
+			if n, l := intValue(v.Field(f.lenIndex)), length(fv, &f.tag); n != int64(l) {
+				return fmt.Errorf("%w: %s has length %d while %s is %d", ErrLengthMismatch, f.name, l, f.tag.LenField, n)
+			}
+		}
+		fieldStart := e.offset()
+		pos := len(e.buf)
+		for j, c := range spec.checksums {
+			switch i {
+			case c.start:
+				sums[j].start = pos
+			case c.field:
+				// written once computed
+				fv = reflect.New(fv.Type()).Elem()
+			}
+		}
+		if f.magic.IsValid() {
+			if fv.IsZero() {
+				fv = f.magic
+			} else if err := checkMagic(f, fv); err != nil {
+				return e.fieldError(err, f.name, fieldStart)
+			}
+		}
+		if f.group != nil {
+			if err := e.encodeBits(v, f.group, fieldOrder(order, &f.tag)); err != nil {
+				return err
+			}
+		} else if f.pad >= 0 {
+			e.buf = append(e.buf, make([]byte, f.pad)...)
+		} else if err := e.encode(fv, order, &f.tag); err != nil {
+			return e.fieldError(err, f.name, fieldStart)
+		}
+		e.sumFields(spec, sums, i, pos, order)
+	}
+	return nil
+}
+
+// encodeBits packs the fields of a bit-field group of struct v into their
+// backing integer
+func (e *encoder) encodeBits(v reflect.Value, g *bitGroup, order Endianness) error {
+	var packed uint64
+	start := e.offset()
+	for _, bf := range g.fields {
+		x, err := bitsValue(v.Field(bf.index), bf.bits)
+		if err != nil {
+			return e.fieldError(err, bf.name, start)
+		}
+		packed |= x << bf.shift
+	}
+	backing := reflect.New(kindTypes[g.kind]).Elem()
+	backing.SetUint(packed)
+	return e.encodePrimitive(backing, order)
+}
+
+// bitsValue returns the value of v as a bit-field of the given size
+func bitsValue(v reflect.Value, bits uint) (uint64, error) {
+	mask := uint64(1)<<bits - 1
+	switch {
+	case v.Kind() == reflect.Bool:
+		if v.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	case isUnsigned(v.Kind()):
+		if x := v.Uint(); x&^mask == 0 {
+			return x, nil
+		}
+		return 0, fmt.Errorf("%w: %d does not fit in %d bits", ErrBitsOverflow, v.Uint(), bits)
+	}
+	x := v.Int()
+	if min, max := int64(-1)<<(bits-1), int64(mask>>1); x >= min && x <= max {
+		return uint64(x) & mask, nil
+	}
+	return 0, fmt.Errorf("%w: %d does not fit in %d bits", ErrBitsOverflow, x, bits)
+}
+
+// fieldOrder returns the endianness of a field
+func fieldOrder(order Endianness, t *Tag) Endianness {
+	if t.Order != nil {
+		return t.Order
+	}
+	return order
+}
+
+// offset returns the current offset in the output stream
+func (e *encoder) offset() int64 {
+	return e.base + int64(len(e.buf))
+}
+
+// fieldError wraps an error occurring while encoding the field or element
+// elem starting at offset
+func (e *encoder) fieldError(err error, elem string, offset int64) error {
+	switch fe := err.(type) {
+	case *TagError:
+		return err
+	case *EncodeError:
+		fe.Field = joinPath(elem, fe.Field)
+		return fe
+	}
+	return &EncodeError{elem, offset, err}
+}
+
+func (e *encoder) encodeElements(v reflect.Value, order Endianness, t *Tag) error {
+	if v.Type().Elem().Kind() == reflect.Uint8 && t.Varint == NoVarint {
+		if v.Kind() == reflect.Slice {
+			e.buf = append(e.buf, v.Bytes()...)
+		} else {
+			for i := 0; i < v.Len(); i++ {
+				e.buf = append(e.buf, byte(v.Index(i).Uint()))
+			}
+		}
+		return nil
+	}
+	et := t.elem()
+	for i := 0; i < v.Len(); i++ {
+		elemStart := e.offset()
+		if err := e.encode(v.Index(i), order, et); err != nil {
+			return e.fieldError(err, fmt.Sprintf("[%d]", i), elemStart)
+		}
+	}
+	return nil
+}
+
+// length returns the encoded length of a slice or a string, UTF-16 strings
+// length being counted in code units
+func length(v reflect.Value, t *Tag) int {
+	if v.Kind() == reflect.String && t.UTF16 {
+		return len(utf16.Encode([]rune(v.String())))
+	}
+	return v.Len()
+}
+
+// encodeLength encodes the length prefix of a slice or a string
+func (e *encoder) encodeLength(n int, order Endianness, t *Tag) error {
+	if t.HasLength() {
+		return nil
+	}
+	if t.LenVarint {
+		e.buf = AppendUvarint(e.buf, uint64(n))
+		return nil
+	}
+	length := reflect.New(lenPrefixType(t)).Elem()
+	if isUnsigned(length.Kind()) {
+		length.SetUint(uint64(n))
+		if length.Uint() != uint64(n) {
+			return fmt.Errorf("%w: %d does not fit in %s", ErrLengthOverflow, n, length.Type())
+		}
+	} else {
+		length.SetInt(int64(n))
+		if length.Int() != int64(n) {
+			return fmt.Errorf("%w: %d does not fit in %s", ErrLengthOverflow, n, length.Type())
+		}
+	}
+	return e.encodePrimitive(length, order)
+}
+
+func (e *encoder) encodeSlice(v reflect.Value, order Endianness, t *Tag) error {
+	if t.Size > 0 && v.Len() > t.Size {
+		return fmt.Errorf("%w: slice of length %d while size is %d", ErrLengthOverflow, v.Len(), t.Size)
+	}
+	if err := e.encodeLength(v.Len(), order, t); err != nil {
+		return err
+	}
+	if err := e.encodeElements(v, order, t); err != nil {
+		return err
+	}
+	// zero padding up to size
+	if pad := t.Size - v.Len(); pad > 0 {
+		zero := reflect.MakeSlice(v.Type(), pad, pad)
+		return e.encodeElements(zero, order, t)
+	}
+	return nil
+}
+
+// encodeMap encodes the length of a map followed by its entries sorted by
+// encoded keys, so that the encoding of a map is deterministic
+func (e *encoder) encodeMap(v reflect.Value, order Endianness, t *Tag) error {
+	if err := e.encodeLength(v.Len(), order, t); err != nil {
+		return err
+	}
+
+	type entry struct {
+		start, end int
+		key, value reflect.Value
+	}
+	et := t.elem()
+	ke := &encoder{pointers: e.pointers}
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		start := len(ke.buf)
+		if err := ke.encode(iter.Key(), order, et); err != nil {
+			return e.fieldError(err, fmt.Sprintf("[%v]", iter.Key()), e.offset())
+		}
+		entries = append(entries, entry{start, len(ke.buf), iter.Key(), iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(ke.buf[entries[i].start:entries[i].end], ke.buf[entries[j].start:entries[j].end]) < 0
+	})
+
+	for _, en := range entries {
+		start := e.offset()
+		e.buf = append(e.buf, ke.buf[en.start:en.end]...)
+		// map values are not addressable
+		value := reflect.New(en.value.Type()).Elem()
+		value.Set(en.value)
+		if err := e.encode(value, order, et); err != nil {
+			return e.fieldError(err, fmt.Sprintf("[%v]", en.key), start)
+		}
+	}
+	return nil
+}
+
+// encodePointer encodes a presence byte, 0 for nil pointers, followed by the
+// pointed value
+func (e *encoder) encodePointer(v reflect.Value, order Endianness, t *Tag) error {
+	if v.IsNil() {
+		e.buf = append(e.buf, 0)
+		return nil
+	}
+	// zero sized values may share their address
+	if v.Type().Elem().Size() > 0 {
+		p := v.Pointer()
+		if e.pointers[p] {
+			return fmt.Errorf("%w: %s", ErrPointerCycle, v.Type())
+		}
+		if e.pointers == nil {
+			e.pointers = make(map[uintptr]bool)
+		}
+		e.pointers[p] = true
+		defer delete(e.pointers, p)
+	}
+	e.buf = append(e.buf, 1)
+	return e.encode(v.Elem(), order, t)
+}
+
+// encodeInterface encodes the id of the concrete type of an interface value
+// (c.f. RegisterType) followed by the value
+func (e *encoder) encodeInterface(v reflect.Value, order Endianness, t *Tag) error {
+	if v.IsNil() {
+		e.buf = AppendUvarint(e.buf, 0)
+		return nil
+	}
+	id, ok := typeID(v.Elem().Type())
+	if !ok {
+		return fmt.Errorf("%w: %s is not registered", ErrUnknownType, v.Elem().Type())
+	}
+	e.buf = AppendUvarint(e.buf, id)
+	// interface values are not addressable
+	value := reflect.New(v.Elem().Type()).Elem()
+	value.Set(v.Elem())
+	return e.encode(value, order, t)
+}
+
+func (e *encoder) encodeString(v reflect.Value, order Endianness, t *Tag) error {
+	s := v.String()
+	if t.CString && strings.IndexByte(s, 0) >= 0 {
+		return fmt.Errorf("%w: %q", ErrNulInString, s)
+	}
+
+	// data is the encoded string made of n units
+	var data []byte
+	n, unit := len(s), 1
+	if t.UTF16 {
+		if t.UTF16Order != nil {
+			order = t.UTF16Order
+		}
+		units := utf16.Encode([]rune(s))
+		data = make([]byte, 2*len(units))
+		for i, u := range units {
+			order.PutUint16(data[2*i:], u)
+		}
+		n, unit = len(units), 2
+	}
+
+	switch {
+	case t.CString:
+	case t.Size > 0:
+		if n > t.Size {
+			return fmt.Errorf("%w: string of length %d while size is %d", ErrLengthOverflow, n, t.Size)
+		}
+	default:
+		if err := e.encodeLength(n, order, t); err != nil {
+			return err
+		}
+	}
+
+	if data != nil {
+		e.buf = append(e.buf, data...)
+	} else {
+		e.buf = append(e.buf, s...)
+	}
+
+	switch {
+	case t.CString:
+		e.buf = append(e.buf, make([]byte, unit)...)
+	case t.Size > n:
+		e.buf = append(e.buf, make([]byte, (t.Size-n)*unit)...)
+	}
+	return nil
+}
+
+// AppendUvarint appends x encoded as unsigned LEB128 to b
+func AppendUvarint(b []byte, x uint64) []byte {
+	for x >= 0x80 {
+		b = append(b, byte(x)|0x80)
+		x >>= 7
+	}
+	return append(b, byte(x))
+}
+
+// AppendVarint appends x encoded as signed LEB128 to b
+func AppendVarint(b []byte, x int64) []byte {
+	for {
+		c := byte(x & 0x7f)
+		x >>= 7
+		if (x == 0 && c&0x40 == 0) || (x == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+// encodeVarint encodes an integer as a LEB128 variable length integer
+func (e *encoder) encodeVarint(v reflect.Value, kind Varint) error {
+	switch {
+	case kind == Uleb128 && isUnsigned(v.Kind()):
+		e.buf = AppendUvarint(e.buf, v.Uint())
+	case kind == Uleb128:
+		if v.Int() < 0 {
+			return fmt.Errorf("%w: cannot encode negative value %d as uleb128", ErrVarintOverflow, v.Int())
+		}
+		e.buf = AppendUvarint(e.buf, uint64(v.Int()))
+	case isUnsigned(v.Kind()):
+		if v.Uint() > math.MaxInt64 {
+			return fmt.Errorf("%w: cannot encode %d as sleb128", ErrVarintOverflow, v.Uint())
+		}
+		e.buf = AppendVarint(e.buf, int64(v.Uint()))
+	default:
+		e.buf = AppendVarint(e.buf, v.Int())
+	}
+	return nil
+}
+
+func (e *encoder) encodePrimitive(v reflect.Value, order Endianness) error {
+	b := e.scratch[:]
+	switch v.Kind() {
+	case reflect.Bool:
+		b[0] = 0
+		if v.Bool() {
+			b[0] = 1
+		}
+		b = b[:1]
+	case reflect.Int8:
+		b[0] = byte(v.Int())
+		b = b[:1]
+	case reflect.Uint8:
+		b[0] = byte(v.Uint())
+		b = b[:1]
+	case reflect.Int16:
+		order.PutUint16(b, uint16(v.Int()))
+		b = b[:2]
+	case reflect.Uint16:
+		order.PutUint16(b, uint16(v.Uint()))
+		b = b[:2]
+	case reflect.Int32:
+		order.PutUint32(b, uint32(v.Int()))
+		b = b[:4]
+	case reflect.Uint32:
+		order.PutUint32(b, uint32(v.Uint()))
+		b = b[:4]
+	case reflect.Int64:
+		order.PutUint64(b, uint64(v.Int()))
+		b = b[:8]
+	case reflect.Uint64:
+		order.PutUint64(b, v.Uint())
+		b = b[:8]
+	case reflect.Float32:
+		order.PutUint32(b, math.Float32bits(float32(v.Float())))
+		b = b[:4]
+	case reflect.Float64:
+		order.PutUint64(b, math.Float64bits(v.Float()))
+		b = b[:8]
+	case reflect.Complex64:
+		c := v.Complex()
+		order.PutUint32(b, math.Float32bits(float32(real(c))))
+		order.PutUint32(b[4:], math.Float32bits(float32(imag(c))))
+		b = b[:8]
+	case reflect.Complex128:
+		c := v.Complex()
+		order.PutUint64(b, math.Float64bits(real(c)))
+		order.PutUint64(b[8:], math.Float64bits(imag(c)))
+	default:
+		return &UnsupportedTypeError{v.Type()}
+	}
+	e.buf = append(e.buf, b...)
+	return nil
+}
+
+// addrInterface returns a pointer to v as an interface, nil if v is not
+// addressable
+func addrInterface(v reflect.Value) interface{} {
+	if v.CanAddr() {
+		if p := v.Addr(); p.CanInterface() {
+			return p.Interface()
+		}
+	}
+	return nil
+}
+
+func isUnsigned(k reflect.Kind) bool {
+	switch k {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// intValue returns the value of any integer as int64
+func intValue(v reflect.Value) int64 {
+	if isUnsigned(v.Kind()) {
+		return int64(v.Uint())
+	}
+	return v.Int()
+}
+
+// lenPrefixType returns the type of the length prefix defined by a tag
+func lenPrefixType(t *Tag) reflect.Type {
+	if t.LenPrefix == reflect.Invalid {
+		return kindTypes[reflect.Int64]
+	}
+	return kindTypes[t.LenPrefix]
+}
+
+var (
+	kindTypes = map[reflect.Kind]reflect.Type{
+		reflect.Uint8:  reflect.TypeOf(uint8(0)),
+		reflect.Uint16: reflect.TypeOf(uint16(0)),
+		reflect.Uint32: reflect.TypeOf(uint32(0)),
+		reflect.Uint64: reflect.TypeOf(uint64(0)),
+		reflect.Int8:   reflect.TypeOf(int8(0)),
+		reflect.Int16:  reflect.TypeOf(int16(0)),
+		reflect.Int32:  reflect.TypeOf(int32(0)),
+		reflect.Int64:  reflect.TypeOf(int64(0)),
+	}
+)
@@ -0,0 +1,82 @@
+package encoding
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	// ErrUnknownType is returned when encoding an interface holding a type
+	// which is not registered or when decoding an unknown type id
+	ErrUnknownType = errors.New("Unknown type")
+	// ErrTypeRegistered is returned when registering a type or an id twice
+	ErrTypeRegistered = errors.New("Type already registered")
+	// ErrInvalidTypeID is returned when registering a type with id 0, which
+	// is reserved for nil interfaces
+	ErrInvalidTypeID = errors.New("Invalid type id")
+	// ErrPointerCycle is returned when encoding a pointer referencing itself
+	ErrPointerCycle = errors.New("Pointer cycle")
+
+	registry = typeRegistry{
+		types: make(map[uint64]reflect.Type),
+		ids:   make(map[reflect.Type]uint64),
+	}
+)
+
+// typeRegistry maps the concrete types stored in interfaces to their ids
+type typeRegistry struct {
+	sync.RWMutex
+	types map[uint64]reflect.Type
+	ids   map[reflect.Type]uint64
+}
+
+// RegisterType registers the type of v with id so that values of this type
+// can be encoded in interface fields. Interface values are encoded as the
+// uleb128 id of their concrete type followed by the value, a nil interface
+// being encoded as id 0. Types must be registered with the same ids by the
+// encoding and the decoding programs.
+func RegisterType(id uint64, v interface{}) error {
+	if id == 0 {
+		return ErrInvalidTypeID
+	}
+	typ := reflect.TypeOf(v)
+	if typ == nil {
+		return fmt.Errorf("%w: cannot register nil", ErrUnknownType)
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if other, ok := registry.types[id]; ok {
+		return fmt.Errorf("%w: id %d used by %s", ErrTypeRegistered, id, other)
+	}
+	if other, ok := registry.ids[typ]; ok {
+		return fmt.Errorf("%w: %s has id %d", ErrTypeRegistered, typ, other)
+	}
+	registry.types[id] = typ
+	registry.ids[typ] = id
+	return nil
+}
+
+// MustRegisterType is like RegisterType but panics on error, it is meant to
+// be used in init functions
+func MustRegisterType(id uint64, v interface{}) {
+	if err := RegisterType(id, v); err != nil {
+		panic(err)
+	}
+}
+
+func typeID(typ reflect.Type) (uint64, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	id, ok := registry.ids[typ]
+	return id, ok
+}
+
+func typeByID(id uint64) (reflect.Type, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	typ, ok := registry.types[id]
+	return typ, ok
+}
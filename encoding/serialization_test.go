@@ -3,8 +3,13 @@ package encoding
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/0xrawsec/golang-utils/log"
@@ -101,3 +106,929 @@ func TestUnmarshalInitSlice(t *testing.T) {
 		t.Error("Test failed")
 	}
 }
+
+type TaggedHeader struct {
+	Magic    uint16 `bin:"be"`
+	Count    uint8
+	Entries  []uint32 `bin:"len=Count"`
+	Name     string   `bin:"size=8"`
+	Skipped  int      `bin:"-"`
+	Comment  string   `bin:"lenprefix=u16"`
+	Data     []byte   `bin:"lenprefix=u8,be"`
+	Reserved []byte   `bin:"size=4"`
+}
+
+func TestMarshalTags(t *testing.T) {
+	h := TaggedHeader{
+		Magic:    0x4D5A,
+		Count:    2,
+		Entries:  []uint32{1, 2},
+		Name:     "foo",
+		Skipped:  42,
+		Comment:  "bar",
+		Data:     []byte{0xca, 0xfe},
+		Reserved: []byte{1}}
+	enc, err := Marshal(&h, binary.LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{
+		0x4D, 0x5A,
+		2,
+		1, 0, 0, 0, 2, 0, 0, 0,
+		'f', 'o', 'o', 0, 0, 0, 0, 0,
+		3, 0, 'b', 'a', 'r',
+		2, 0xca, 0xfe,
+		1, 0, 0, 0}
+	if !bytes.Equal(enc, expected) {
+		t.Fatalf("Bad encoding: %q", enc)
+	}
+
+	var nh TaggedHeader
+	if err := Unmarshal(bytes.NewReader(enc), &nh, binary.LittleEndian); err != nil {
+		t.Fatal(err)
+	}
+	h.Skipped = 0
+	h.Reserved = []byte{1, 0, 0, 0}
+	if !reflect.DeepEqual(h, nh) {
+		t.Errorf("Bad decoding: %+v", nh)
+	}
+}
+
+func TestMarshalTagErrors(t *testing.T) {
+	h := TaggedHeader{Count: 3, Entries: []uint32{1}}
+	if _, err := Marshal(&h, binary.LittleEndian); !errors.Is(err, ErrLengthMismatch) {
+		t.Errorf("Expecting length mismatch: %v", err)
+	}
+	h = TaggedHeader{Name: "this name is too long"}
+	if _, err := Marshal(&h, binary.LittleEndian); !errors.Is(err, ErrLengthOverflow) {
+		t.Errorf("Expecting length overflow: %v", err)
+	}
+	h = TaggedHeader{Data: make([]byte, 256)}
+	if _, err := Marshal(&h, binary.LittleEndian); !errors.Is(err, ErrLengthOverflow) {
+		t.Errorf("Expecting length overflow: %v", err)
+	}
+
+	bad := struct {
+		Data  []byte `bin:"len=Count"`
+		Count uint8
+	}{}
+	_, err := Marshal(&bad, binary.LittleEndian)
+	if _, ok := err.(*TagError); !ok {
+		t.Errorf("Expecting tag error: %v", err)
+	}
+	t.Log(err)
+}
+
+func TestPadding(t *testing.T) {
+	type padded struct {
+		A uint16
+		_ [2]byte
+		B uint32
+		_ uint8
+	}
+	p := padded{A: 0x1234, B: 0xdeadbeef}
+	b, err := Marshal(&p, binary.LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, []byte{0x34, 0x12, 0, 0, 0xef, 0xbe, 0xad, 0xde, 0}) {
+		t.Errorf("Bad encoding: %x", b)
+	}
+	var np padded
+	if err := Unmarshal(bytes.NewReader([]byte{0x34, 0x12, 0xff, 0xff, 0xef, 0xbe, 0xad, 0xde, 0xff}), &np, binary.LittleEndian); err != nil {
+		t.Fatal(err)
+	}
+	if np != p {
+		t.Errorf("Bad decoding: %+v", np)
+	}
+
+	unexported := struct {
+		A uint16
+		b uint32
+	}{}
+	if _, err := Marshal(&unexported, binary.LittleEndian); !errors.As(err, new(*TagError)) {
+		t.Errorf("Expecting tag error: %v", err)
+	}
+	variable := struct {
+		A uint16
+		_ []byte
+	}{}
+	if _, err := Marshal(&variable, binary.LittleEndian); !errors.As(err, new(*TagError)) {
+		t.Errorf("Expecting tag error: %v", err)
+	}
+}
+
+func TestUnpackOffset(t *testing.T) {
+	var u uint32
+	reader := bytes.NewReader([]byte{0, 0, 0, 0, 0x78, 0x56, 0x34, 0x12})
+	if err := Unpack(reader, binary.LittleEndian, &u, 4); err != nil {
+		t.Fatal(err)
+	}
+	if u != 0x12345678 {
+		t.Errorf("Bad value unpacked at offset: 0x%x", u)
+	}
+}
+
+type PEHeader struct {
+	Signature [4]byte
+	Machine   uint16
+}
+
+type DOSHeader struct {
+	Magic  [2]byte
+	Lfanew uint32   `bin:"offset=0x3c"`
+	PE     PEHeader `bin:"offset=Lfanew"`
+	// back to the header start
+	Magic2 [2]byte `bin:"offset=0"`
+}
+
+func TestOffsetTags(t *testing.T) {
+	h := DOSHeader{
+		Magic:  [2]byte{'M', 'Z'},
+		Lfanew: 0x80,
+		PE:     PEHeader{[4]byte{'P', 'E'}, 0x14c}}
+	h.Magic2 = h.Magic
+
+	// cannot encode backward
+	if _, err := Marshal(&h, binary.LittleEndian); !errors.Is(err, ErrOffsetOverlap) {
+		t.Errorf("Expecting offset overlap: %v", err)
+	}
+
+	// building file with an unrelated prefix
+	data := make([]byte, 0x100)
+	copy(data[0x10:], "MZ")
+	binary.LittleEndian.PutUint32(data[0x10+0x3c:], 0x80)
+	copy(data[0x10+0x80:], "PE\x00\x00\x4c\x01")
+
+	var nh DOSHeader
+	if err := UnmarshalAt(bytes.NewReader(data), 0x10, &nh, binary.LittleEndian); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(h, nh) {
+		t.Errorf("Bad decoding: %+v", nh)
+	}
+
+	// non seekable readers only move forward
+	reader := io.MultiReader(bytes.NewReader(data[0x10:]))
+	if err := Unmarshal(reader, &nh, binary.LittleEndian); !errors.Is(err, ErrSeeking) {
+		t.Errorf("Expecting seeking error: %v", err)
+	}
+
+	forward := struct {
+		Magic  [2]byte
+		Lfanew uint32   `bin:"offset=0x3c"`
+		PE     PEHeader `bin:"offset=Lfanew"`
+	}{h.Magic, h.Lfanew, h.PE}
+	enc, err := Marshal(&forward, binary.LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(enc, data[0x10:0x10+0x86]) {
+		t.Errorf("Bad encoding: %q", enc)
+	}
+	reader = io.MultiReader(bytes.NewReader(enc))
+	if err := Unmarshal(reader, &forward, binary.LittleEndian); err != nil {
+		t.Error(err)
+	}
+}
+
+type StringRecord struct {
+	Unsigned uint32   `bin:"uleb128"`
+	Signed   int64    `bin:"sleb128"`
+	Values   []uint64 `bin:"uleb128,lenprefix=uleb128"`
+	CString  string   `bin:"cstring"`
+	Name     string   `bin:"utf16le,cstring"`
+	Fixed    string   `bin:"utf16be,size=4"`
+	NameLen  uint16
+	Wide     string   `bin:"utf16,len=NameLen"`
+	Prefixed string   `bin:"lenprefix=u8"`
+	Strings  []string `bin:"cstring,lenprefix=u8"`
+}
+
+func TestVarintStrings(t *testing.T) {
+	r := StringRecord{
+		Unsigned: 624485,
+		Signed:   -123456,
+		Values:   []uint64{0, 127, 128, 1<<64 - 1},
+		CString:  "foo",
+		Name:     "héllo",
+		Fixed:    "ab",
+		NameLen:  3,
+		Wide:     "€🙂",
+		Prefixed: "bar",
+		Strings:  []string{"a", "bc"}}
+	enc, err := Marshal(&r, binary.LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{
+		0xe5, 0x8e, 0x26,
+		0xc0, 0xbb, 0x78,
+		4, 0, 0x7f, 0x80, 0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01,
+		'f', 'o', 'o', 0,
+		'h', 0, 0xe9, 0, 'l', 0, 'l', 0, 'o', 0, 0, 0,
+		0, 'a', 0, 'b', 0, 0, 0, 0,
+		3, 0,
+		0xac, 0x20, 0x3d, 0xd8, 0x42, 0xde,
+		3, 'b', 'a', 'r',
+		2, 'a', 0, 'b', 'c', 0}
+	if !bytes.Equal(enc, expected) {
+		t.Fatalf("Bad encoding:\n%v\n%v", enc, expected)
+	}
+
+	var nr StringRecord
+	if err := Unmarshal(bytes.NewReader(enc), &nr, binary.LittleEndian); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r, nr) {
+		t.Errorf("Bad decoding: %+v", nr)
+	}
+}
+
+func TestVarintErrors(t *testing.T) {
+	small := struct {
+		U uint8 `bin:"uleb128"`
+	}{}
+	if err := Unmarshal(bytes.NewReader([]byte{0x80, 0x02}), &small, binary.LittleEndian); !errors.Is(err, ErrVarintOverflow) {
+		t.Errorf("Expecting varint overflow: %v", err)
+	}
+	long := bytes.Repeat([]byte{0xff}, 11)
+	if err := Unmarshal(bytes.NewReader(long), &small, binary.LittleEndian); !errors.Is(err, ErrVarintOverflow) {
+		t.Errorf("Expecting varint overflow: %v", err)
+	}
+	negative := struct {
+		I int32 `bin:"uleb128"`
+	}{-1}
+	if _, err := Marshal(&negative, binary.LittleEndian); !errors.Is(err, ErrVarintOverflow) {
+		t.Errorf("Expecting varint overflow: %v", err)
+	}
+	nul := struct {
+		S string `bin:"cstring"`
+	}{"foo\x00bar"}
+	if _, err := Marshal(&nul, binary.LittleEndian); !errors.Is(err, ErrNulInString) {
+		t.Errorf("Expecting NUL in string error: %v", err)
+	}
+	bad := struct {
+		S float32 `bin:"uleb128"`
+	}{}
+	if _, err := Marshal(&bad, binary.LittleEndian); err == nil {
+		t.Error("Expecting tag error")
+	}
+}
+
+type StreamEntry struct {
+	ID   uint32
+	Name string `bin:"lenprefix=u8"`
+}
+
+type StreamRecord struct {
+	Magic   uint16
+	Entries []StreamEntry `bin:"lenprefix=u8"`
+}
+
+func TestEncoderDecoder(t *testing.T) {
+	buf := new(bytes.Buffer)
+	enc := NewEncoder(buf, binary.BigEndian)
+	records := make([]StreamRecord, 10)
+	for i := range records {
+		records[i].Magic = uint16(i)
+		records[i].Entries = make([]StreamEntry, 0, i)
+		for j := 0; j < i; j++ {
+			records[i].Entries = append(records[i].Entries, StreamEntry{uint32(j), fmt.Sprintf("entry-%d", j)})
+		}
+		if err := enc.Encode(&records[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if enc.Offset() != int64(buf.Len()) {
+		t.Errorf("Bad encoder offset %d", enc.Offset())
+	}
+
+	dec := NewDecoder(bytes.NewReader(buf.Bytes()), binary.BigEndian)
+	for i := 0; ; i++ {
+		var r StreamRecord
+		err := dec.Decode(&r)
+		if err == io.EOF {
+			if i != len(records) {
+				t.Errorf("Decoded %d records instead of %d", i, len(records))
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(r.Entries, records[i].Entries) || r.Magic != records[i].Magic {
+			t.Errorf("Bad record: %+v", r)
+		}
+	}
+	if dec.Offset() != int64(buf.Len()) {
+		t.Errorf("Bad decoder offset %d", dec.Offset())
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	r := StreamRecord{Magic: 0x4242, Entries: []StreamEntry{{1, "foo"}, {2, "bar"}}}
+	data, err := Marshal(&r, binary.LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// truncate in the middle of the name of the second entry
+	dec := NewDecoder(bytes.NewReader(data[:len(data)-1]), binary.LittleEndian)
+	err = dec.Decode(&r)
+	var derr *DecodeError
+	if !errors.As(err, &derr) {
+		t.Fatalf("Expecting decode error: %v", err)
+	}
+	// magic (2) + length (1) + first entry (8) + second entry id (4)
+	if derr.Field != "Entries[1].Name" || derr.Offset != 15 {
+		t.Errorf("Bad decode error: %v", derr)
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expecting unexpected EOF: %v", err)
+	}
+	t.Log(err)
+
+	r.Entries[0].Name = strings.Repeat("A", 256)
+	enc := NewEncoder(ioutil.Discard, binary.LittleEndian)
+	err = enc.Encode(&r)
+	var eerr *EncodeError
+	if !errors.As(err, &eerr) || eerr.Field != "Entries[0].Name" || eerr.Offset != 7 {
+		t.Errorf("Bad encode error: %v", err)
+	}
+	if !errors.Is(err, ErrLengthOverflow) {
+		t.Errorf("Expecting length overflow: %v", err)
+	}
+	if enc.Offset() != 0 {
+		t.Errorf("Nothing should be written on error")
+	}
+}
+
+type IPv4Header struct {
+	Version     uint8 `bin:"bits=4,bitorder=msb"`
+	IHL         uint8 `bin:"bits=4"`
+	TOS         uint8
+	TotalLength uint16 `bin:"be"`
+	ID          uint16 `bin:"be"`
+	Flags       uint16 `bin:"be,bits=3,bitorder=msb"`
+	FragOffset  uint16 `bin:"bits=13"`
+	TTL         uint8
+}
+
+type PackedFlags struct {
+	Enabled bool  `bin:"bits=1"`
+	Mode    uint8 `bin:"bits=3"`
+	Delta   int8  `bin:"bits=4"`
+	// new group as it does not fit in the previous one
+	Level int16 `bin:"bits=5"`
+	_     int16 `bin:"bits=3"`
+	Count int16 `bin:"bits=8"`
+}
+
+func TestBitFields(t *testing.T) {
+	h := IPv4Header{Version: 4, IHL: 5, TOS: 0, TotalLength: 60, ID: 0x1c46, Flags: 2, FragOffset: 0x123, TTL: 64}
+	enc, err := Marshal(&h, binary.LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{0x45, 0x00, 0x00, 0x3c, 0x1c, 0x46, 0x41, 0x23, 0x40}
+	if !bytes.Equal(enc, expected) {
+		t.Fatalf("Bad encoding: %x", enc)
+	}
+	var nh IPv4Header
+	if err := Unmarshal(bytes.NewReader(enc), &nh, binary.LittleEndian); err != nil {
+		t.Fatal(err)
+	}
+	if h != nh {
+		t.Errorf("Bad decoding: %+v", nh)
+	}
+
+	f := PackedFlags{Enabled: true, Mode: 5, Delta: -3, Level: -16, Count: -1}
+	if enc, err = Marshal(&f, binary.LittleEndian); err != nil {
+		t.Fatal(err)
+	}
+	// 1 | 101 << 1 | 1101 << 4 and 10000 | 000 << 5 | 11111111 << 8
+	expected = []byte{0xdb, 0x10, 0xff}
+	if !bytes.Equal(enc, expected) {
+		t.Fatalf("Bad encoding: %x", enc)
+	}
+	var nf PackedFlags
+	if err := Unmarshal(bytes.NewReader(enc), &nf, binary.LittleEndian); err != nil {
+		t.Fatal(err)
+	}
+	if f != nf {
+		t.Errorf("Bad decoding: %+v", nf)
+	}
+}
+
+func TestBitFieldErrors(t *testing.T) {
+	h := IPv4Header{Version: 16}
+	_, err := Marshal(&h, binary.LittleEndian)
+	var eerr *EncodeError
+	if !errors.Is(err, ErrBitsOverflow) || !errors.As(err, &eerr) || eerr.Field != "Version" {
+		t.Errorf("Expecting bits overflow on Version: %v", err)
+	}
+	f := PackedFlags{Delta: 8}
+	if _, err := Marshal(&f, binary.LittleEndian); !errors.Is(err, ErrBitsOverflow) {
+		t.Errorf("Expecting bits overflow: %v", err)
+	}
+
+	for _, v := range []interface{}{
+		&struct {
+			F float32 `bin:"bits=3"`
+		}{},
+		&struct {
+			A uint8 `bin:"bits=9"`
+		}{},
+		&struct {
+			A uint8 `bin:"bits=4"`
+			B uint8 `bin:"bits=4,bitorder=msb"`
+		}{},
+		&struct {
+			A uint8 `bin:"bitorder=msb"`
+		}{},
+	} {
+		_, err := Marshal(v, binary.LittleEndian)
+		if _, ok := err.(*TagError); !ok {
+			t.Errorf("Expecting tag error for %T: %v", v, err)
+		}
+	}
+}
+
+type TreeNode struct {
+	Value    uint8
+	Children []TreeNode `bin:"lenprefix=u8"`
+}
+
+type FuzzRecord struct {
+	Magic   uint32
+	Kind    uint16 `bin:"uleb128"`
+	Flags   uint8  `bin:"bits=3"`
+	Mode    int8   `bin:"bits=5"`
+	Name    string `bin:"lenprefix=u16"`
+	Comment string `bin:"utf16le,cstring"`
+	Values  []int64
+	Nodes   []TreeNode `bin:"lenprefix=uleb128"`
+	NData   uint32
+	Data    []byte              `bin:"len=NData"`
+	Lists   map[uint8]*ListNode `bin:"lenprefix=u8"`
+	Shape   Shape
+}
+
+func TestDecodeLimits(t *testing.T) {
+	var data []byte
+	// negative length prefix
+	negative := append([]byte{0, 0, 0, 0}, bytes.Repeat([]byte{0xff}, 8)...)
+	err := Unmarshal(bytes.NewReader(negative), &struct {
+		Magic uint32
+		Data  []uint32
+	}{}, binary.LittleEndian)
+	if !errors.Is(err, ErrNegativeLength) {
+		t.Errorf("Expecting negative length error: %v", err)
+	}
+
+	// huge length with few data must not allocate the whole length
+	huge := []byte{0, 0, 0, 0, 0, 0, 0, 0x10, 1, 2, 3}
+	if err := Unmarshal(bytes.NewReader(huge), &data, binary.BigEndian); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expecting unexpected EOF: %v", err)
+	}
+	var s string
+	if err := Unmarshal(bytes.NewReader(huge), &s, binary.BigEndian); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expecting unexpected EOF: %v", err)
+	}
+	var values []uint64
+	if err := Unmarshal(bytes.NewReader(huge), &values, binary.BigEndian); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expecting unexpected EOF: %v", err)
+	}
+
+	// huge length of zero sized elements without any limit
+	empty := []byte{0x40, 0, 0, 0, 0, 0, 0, 0}
+	var structs []struct{}
+	if err := Unmarshal(bytes.NewReader(empty), &structs, binary.BigEndian); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expecting limit error: %v", err)
+	}
+	binary.BigEndian.PutUint64(empty, 3)
+	if err := Unmarshal(bytes.NewReader(empty), &structs, binary.BigEndian); err != nil || len(structs) != 3 {
+		t.Errorf("Bad decoding of zero sized elements: %v", err)
+	}
+
+	// slice length and allocation
+	data = make([]byte, 64)
+	enc, _ := Marshal(&data, binary.LittleEndian)
+	for _, l := range []Limits{{MaxSliceLen: 63}, {MaxAlloc: 63}} {
+		dec := NewDecoder(bytes.NewReader(enc), binary.LittleEndian)
+		dec.SetLimits(l)
+		err := dec.Decode(&data)
+		var lerr *LimitError
+		if !errors.Is(err, ErrLimitExceeded) || !errors.As(err, &lerr) {
+			t.Errorf("Expecting limit error: %v", err)
+		}
+		t.Log(err)
+	}
+
+	// allocation is accounted per decoded value
+	dec := NewDecoder(bytes.NewReader(append(enc, enc...)), binary.LittleEndian)
+	dec.SetLimits(Limits{MaxAlloc: 64})
+	for i := 0; i < 2; i++ {
+		if err := dec.Decode(&data); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// nesting depth
+	nested := bytes.Repeat([]byte{0, 1}, 100)
+	var node TreeNode
+	dec = NewDecoder(bytes.NewReader(nested), binary.LittleEndian)
+	dec.SetLimits(DefaultLimits)
+	if err := dec.Decode(&node); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expecting limit error: %v", err)
+	}
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	r := FuzzRecord{
+		Magic:   0xdeadbeef,
+		Kind:    300,
+		Flags:   5,
+		Mode:    -3,
+		Name:    "fuzz",
+		Comment: "comment",
+		Values:  []int64{1, -1},
+		Nodes:   []TreeNode{{1, []TreeNode{{2, nil}}}},
+		NData:   3,
+		Data:    []byte{1, 2, 3},
+		Lists:   map[uint8]*ListNode{1: {1, nil}, 2: nil},
+		Shape:   &Rect{1, 2}}
+	for _, order := range []Endianness{binary.LittleEndian, binary.BigEndian} {
+		seed, err := Marshal(&r, order)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(seed)
+	}
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var r FuzzRecord
+		dec := NewDecoder(bytes.NewReader(data), binary.LittleEndian)
+		dec.SetLimits(DefaultLimits)
+		if err := dec.Decode(&r); err != nil {
+			return
+		}
+		// successfully decoded records can be encoded again
+		if _, err := Marshal(&r, binary.LittleEndian); err != nil {
+			t.Errorf("Cannot encode decoded record: %v", err)
+		}
+	})
+}
+
+type Shape interface {
+	Area() float64
+}
+
+type Square struct {
+	Side float64
+}
+
+func (s Square) Area() float64 { return s.Side * s.Side }
+
+type Rect struct {
+	W, H float64
+}
+
+func (r *Rect) Area() float64 { return r.W * r.H }
+
+type ListNode struct {
+	Value uint32
+	Next  *ListNode
+}
+
+type Index struct {
+	Terms   map[string][]uint32 `bin:"lenprefix=uleb128"`
+	Weights map[uint16]float32  `bin:"uleb128"`
+	Flags   []bool
+	Parent  *Index
+	Head    *ListNode
+	Shapes  []Shape
+	Default Shape
+}
+
+func init() {
+	MustRegisterType(1, Square{})
+	MustRegisterType(2, &Rect{})
+}
+
+func TestMapsPointersInterfaces(t *testing.T) {
+	idx := Index{
+		Terms:   map[string][]uint32{"foo": {1, 2}, "bar": {3}, "baz": nil},
+		Weights: map[uint16]float32{300: 0.5, 1: 1},
+		Flags:   []bool{true, false, true},
+		Parent:  &Index{Flags: []bool{}},
+		Head:    &ListNode{1, &ListNode{2, nil}},
+		Shapes:  []Shape{Square{2}, &Rect{2, 3}, nil}}
+
+	enc, err := Marshal(&idx, binary.LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// map entries are sorted so the encoding is deterministic
+	for i := 0; i < 10; i++ {
+		if other, _ := Marshal(&idx, binary.LittleEndian); !bytes.Equal(enc, other) {
+			t.Fatal("Encoding is not deterministic")
+		}
+	}
+	// 3 entries with sorted keys bar < baz < foo, prefixed by their length
+	if !bytes.Equal(enc[:9], []byte{3, 3, 0, 0, 0, 0, 0, 0, 0}) || string(enc[9:12]) != "bar" {
+		t.Errorf("Bad map encoding: %v", enc[:32])
+	}
+
+	var nidx Index
+	if err := Unmarshal(bytes.NewReader(enc), &nidx, binary.LittleEndian); err != nil {
+		t.Fatal(err)
+	}
+	// nil slices are decoded as empty ones
+	idx.Terms["baz"] = []uint32{}
+	idx.Parent.Terms, idx.Parent.Weights = map[string][]uint32{}, map[uint16]float32{}
+	idx.Parent.Shapes = []Shape{}
+	if !reflect.DeepEqual(idx, nidx) {
+		t.Errorf("Bad decoding:\n%+v\n%+v", idx, nidx)
+	}
+	if nidx.Shapes[1].Area() != 6 {
+		t.Errorf("Bad interface value: %+v", nidx.Shapes[1])
+	}
+}
+
+func TestMapsPointersInterfacesErrors(t *testing.T) {
+	type unregistered struct{ Square }
+	idx := Index{Default: unregistered{}}
+	if _, err := Marshal(&idx, binary.LittleEndian); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("Expecting unknown type error: %v", err)
+	}
+
+	head := &ListNode{Value: 1}
+	head.Next = &ListNode{2, head}
+	if _, err := Marshal(&head, binary.LittleEndian); !errors.Is(err, ErrPointerCycle) {
+		t.Errorf("Expecting pointer cycle error: %v", err)
+	}
+
+	if err := RegisterType(1, Rect{}); !errors.Is(err, ErrTypeRegistered) {
+		t.Errorf("Expecting type registered error: %v", err)
+	}
+	if err := RegisterType(42, Square{}); !errors.Is(err, ErrTypeRegistered) {
+		t.Errorf("Expecting type registered error: %v", err)
+	}
+	if err := RegisterType(0, ListNode{}); !errors.Is(err, ErrInvalidTypeID) {
+		t.Errorf("Expecting invalid type id error: %v", err)
+	}
+
+	var shape Shape
+	if err := Unmarshal(bytes.NewReader([]byte{42}), &shape, binary.LittleEndian); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("Expecting unknown type error: %v", err)
+	}
+	var node *ListNode
+	if err := Unmarshal(bytes.NewReader([]byte{2}), &node, binary.LittleEndian); !errors.Is(err, ErrInvalidPresence) {
+		t.Errorf("Expecting invalid presence error: %v", err)
+	}
+
+	// a self referencing list must hit the depth limit
+	dec := NewDecoder(bytes.NewReader(bytes.Repeat([]byte{1, 0, 0, 0, 0}, 100)), binary.LittleEndian)
+	dec.SetLimits(DefaultLimits)
+	if err := dec.Decode(&node); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expecting limit error: %v", err)
+	}
+}
+
+type DumpRecord struct {
+	Magic  [4]byte
+	Header IPv4Header
+	Name   string `bin:"cstring"`
+	Values []int16
+	Next   *ListNode
+}
+
+func TestDump(t *testing.T) {
+	r := DumpRecord{
+		Magic:  [4]byte{'D', 'U', 'M', 'P'},
+		Header: IPv4Header{Version: 4, IHL: 5, TTL: 64},
+		Name:   "foo",
+		Values: []int16{-1, 0x1234},
+		Next:   &ListNode{Value: 7}}
+	enc, err := Marshal(&r, binary.LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var nr DumpRecord
+	out := new(bytes.Buffer)
+	if err := Dump(out, bytes.NewReader(enc), &nr, binary.LittleEndian); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r, nr) {
+		t.Errorf("Bad decoding: %+v", nr)
+	}
+	expected := []string{
+		`00000000  44 55 4d 50                                      Magic = "DUMP"`,
+		`00000004  45                                               Header.Version = 4 (0x4)`,
+		`00000004                                                   Header.IHL = 5 (0x5)`,
+		`00000005  00                                               Header.TOS = 0 (0x0)`,
+		`00000006  00 00                                            Header.TotalLength = 0 (0x0)`,
+		`00000008  00 00                                            Header.ID = 0 (0x0)`,
+		`0000000a  00 00                                            Header.Flags = 0 (0x0)`,
+		`0000000a                                                   Header.FragOffset = 0 (0x0)`,
+		`0000000c  40                                               Header.TTL = 64 (0x40)`,
+		`0000000d  66 6f 6f 00                                      Name = "foo"`,
+		`00000011  02 00 00 00 00 00 00 00                          Values (length) = 2 (0x2)`,
+		`00000019  ff ff                                            Values[0] = -1 (-0x1)`,
+		`0000001b  34 12                                            Values[1] = 4660 (0x1234)`,
+		`0000001d  01                                               Next (present) = true`,
+		`0000001e  07 00 00 00                                      Next.Value = 7 (0x7)`,
+		`00000022  00                                               Next.Next (present) = false`,
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Bad dump:\n%s", out)
+	}
+
+	// bytes read before an error are dumped
+	out.Reset()
+	err = Dump(out, bytes.NewReader(enc[:0x13]), &nr, binary.LittleEndian)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expecting unexpected EOF: %v", err)
+	}
+	if !strings.HasSuffix(out.String(), "00000011  02 00                                            (undecoded)\n") {
+		t.Errorf("Bad dump:\n%s", out)
+	}
+	// offsets account the skipped bytes
+	out.Reset()
+	dec := NewDecoder(bytes.NewReader(append([]byte{0xff, 0xff}, enc...)), binary.LittleEndian)
+	if err := dec.Skip(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Dump(out, &nr); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "00000002  44 55 4d 50") {
+		t.Errorf("Bad dump:\n%s", out)
+	}
+}
+
+func TestParseLayout(t *testing.T) {
+	typ, err := ParseLayout("Magic [4]byte; Count uint16 be\nValues []uint32 len=Count\nName string lenprefix=uleb128")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ.NumField() != 4 || typ.Field(2).Type != reflect.TypeOf([]uint32{}) {
+		t.Fatalf("Bad layout type: %s", typ)
+	}
+
+	data := []byte{'L', 'A', 'Y', 'T', 0, 2, 1, 0, 0, 0, 2, 0, 0, 0, 3, 'f', 'o', 'o'}
+	v := reflect.New(typ)
+	if err := Unmarshal(bytes.NewReader(data), v.Interface(), binary.LittleEndian); err != nil {
+		t.Fatal(err)
+	}
+	if values := v.Elem().Field(2).Interface().([]uint32); !reflect.DeepEqual(values, []uint32{1, 2}) {
+		t.Errorf("Bad decoding: %v", values)
+	}
+	if name := v.Elem().Field(3).String(); name != "foo" {
+		t.Errorf("Bad decoding: %s", name)
+	}
+
+	for _, layout := range []string{
+		"",
+		"Count",
+		"count uint16",
+		"Count uint16; Count uint32",
+		"Count int",
+		"Values [x]byte",
+		"Values []uint32 len=Missing",
+		"Values [100000000000]uint64",
+		"Values [65536][65536]byte",
+	} {
+		if _, err := ParseLayout(layout); err == nil {
+			t.Errorf("Expecting error parsing %q", layout)
+		}
+	}
+}
+
+type Chunk struct {
+	Magic  [4]byte `bin:"magic=CHNK"`
+	Length uint32  `bin:"be"`
+	Kind   uint16  `bin:"magic=0x0102"`
+	Data   []byte  `bin:"len=Length"`
+	CRC    uint32  `bin:"be,crc32=Kind:Data"`
+}
+
+type SummedHeader struct {
+	CRC  uint32 `bin:"crc32=Size:Name"`
+	Size uint16
+	Name string `bin:"cstring"`
+	Tail uint8
+}
+
+func TestMagicChecksum(t *testing.T) {
+	c := Chunk{Length: 3, Data: []byte("foo")}
+	enc, err := Marshal(&c, binary.LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(enc[:4]) != "CHNK" || !bytes.Equal(enc[8:10], []byte{2, 1}) {
+		t.Errorf("Magics not written: %x", enc)
+	}
+	if sum := binary.BigEndian.Uint32(enc[13:]); sum != crc32.ChecksumIEEE(enc[8:13]) {
+		t.Errorf("Bad checksum: %#x", sum)
+	}
+	var nc Chunk
+	if err := Unmarshal(bytes.NewReader(enc), &nc, binary.LittleEndian); err != nil {
+		t.Fatal(err)
+	}
+	c.Magic, c.Kind, c.CRC = [4]byte{'C', 'H', 'N', 'K'}, 0x0102, nc.CRC
+	if !reflect.DeepEqual(c, nc) {
+		t.Errorf("Bad decoding: %+v", nc)
+	}
+
+	// checksum preceding its range
+	h := SummedHeader{CRC: 42, Size: 3, Name: "bar", Tail: 1}
+	if enc, err = Marshal(&h, binary.LittleEndian); err != nil {
+		t.Fatal(err)
+	}
+	if sum := binary.LittleEndian.Uint32(enc); sum != crc32.ChecksumIEEE(enc[4:10]) {
+		t.Errorf("Bad checksum: %#x", sum)
+	}
+	var nh SummedHeader
+	if err := Unmarshal(bytes.NewReader(enc), &nh, binary.LittleEndian); err != nil {
+		t.Fatal(err)
+	}
+	enc[7] = 'z'
+	err = Unmarshal(bytes.NewReader(enc), &nh, binary.LittleEndian)
+	var derr *DecodeError
+	var cerr *ChecksumError
+	if !errors.Is(err, ErrBadChecksum) || !errors.As(err, &derr) || !errors.As(err, &cerr) {
+		t.Fatalf("Expecting checksum error: %v", err)
+	}
+	if derr.Field != "CRC" || derr.Offset != 0 || cerr.Stored != nh.CRC {
+		t.Errorf("Bad checksum error: %v", err)
+	}
+}
+
+func TestMagicChecksumErrors(t *testing.T) {
+	c := Chunk{Magic: [4]byte{'B', 'A', 'D', '!'}}
+	if _, err := Marshal(&c, binary.LittleEndian); !errors.Is(err, ErrBadMagic) {
+		t.Errorf("Expecting bad magic error: %v", err)
+	}
+
+	enc, err := Marshal(&Chunk{}, binary.LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc[9] = 3
+	var nc Chunk
+	err = Unmarshal(bytes.NewReader(enc), &nc, binary.LittleEndian)
+	var merr *MagicError
+	if !errors.As(err, &merr) || merr.Expected != "0x102" || merr.Got != "0x302" {
+		t.Errorf("Expecting bad magic error: %v", err)
+	}
+	enc[9], enc[11] = 1, 0xff
+	if err := Unmarshal(bytes.NewReader(enc), &nc, binary.LittleEndian); !errors.Is(err, ErrBadChecksum) {
+		t.Errorf("Expecting bad checksum error: %v", err)
+	}
+
+	for _, v := range []interface{}{
+		&struct {
+			Sum uint16 `bin:"crc32=A:A"`
+			A   uint8
+		}{},
+		&struct {
+			Sum uint32 `bin:"crc32=A:Missing"`
+			A   uint8
+		}{},
+		&struct {
+			A   uint8
+			B   uint8
+			Sum uint32 `bin:"crc32=B:A"`
+		}{},
+		&struct {
+			A   uint8
+			Sum uint32 `bin:"crc32=A:B"`
+			B   uint8
+		}{},
+		&struct {
+			A   uint8
+			B   uint8  `bin:"offset=4"`
+			Sum uint32 `bin:"crc32=A:B"`
+		}{},
+		&struct {
+			Magic string `bin:"magic=foo"`
+		}{},
+		&struct {
+			Magic [2]byte `bin:"magic=foo"`
+		}{},
+		&struct {
+			Magic uint8 `bin:"magic=0x100"`
+		}{},
+	} {
+		var terr *TagError
+		if _, err := Marshal(v, binary.LittleEndian); !errors.As(err, &terr) {
+			t.Errorf("Expecting tag error for %T: %v", v, err)
+		}
+	}
+}
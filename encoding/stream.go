@@ -0,0 +1,154 @@
+package encoding
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// DecodeError is returned by Decoder when decoding a field fails
+type DecodeError struct {
+	// Field is the path of the field within the decoded value, like
+	// Header.Entries[2].Size
+	Field string
+	// Offset is the offset of the field in the stream
+	Offset int64
+	Err    error
+}
+
+// Error implements error interface
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("Error decoding %s at offset %d: %s", e.Field, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// EncodeError is returned by Encoder when encoding a field fails
+type EncodeError struct {
+	// Field is the path of the field within the encoded value
+	Field string
+	// Offset is the offset of the field in the stream
+	Offset int64
+	Err    error
+}
+
+// Error implements error interface
+func (e *EncodeError) Error() string {
+	return fmt.Sprintf("Error encoding %s at offset %d: %s", e.Field, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+// joinPath prepends elem to a field path
+func joinPath(elem, path string) string {
+	if path == "" || strings.HasPrefix(path, "[") {
+		return elem + path
+	}
+	return elem + "." + path
+}
+
+// pointerElem returns the value pointed by data
+func pointerElem(data interface{}) (reflect.Value, error) {
+	val := reflect.ValueOf(data)
+	if val.Kind() != reflect.Ptr {
+		return val, ErrNoPointerInterface
+	}
+	if val.IsNil() {
+		return val, ErrInvalidNilPointer
+	}
+	return val.Elem(), nil
+}
+
+// Encoder writes encoded values to an output stream
+type Encoder struct {
+	w      io.Writer
+	order  Endianness
+	e      encoder
+	offset int64
+}
+
+// NewEncoder creates a new Encoder writing to w with the given endianness
+func NewEncoder(w io.Writer, endianness Endianness) *Encoder {
+	return &Encoder{w: w, order: endianness}
+}
+
+// Encode writes the encoding of the value pointed by data to the stream
+// (c.f. Marshal). Successive calls write the values one after the other.
+func (enc *Encoder) Encode(data interface{}) error {
+	val, err := pointerElem(data)
+	if err != nil {
+		return err
+	}
+	// reuse the buffer of previous records
+	enc.e.buf = enc.e.buf[:0]
+	enc.e.base = enc.offset
+	if err := enc.e.encode(val, enc.order, &Tag{}); err != nil {
+		return err
+	}
+	n, err := enc.w.Write(enc.e.buf)
+	enc.offset += int64(n)
+	return err
+}
+
+// Offset returns the number of bytes written so far
+func (enc *Encoder) Offset() int64 {
+	return enc.offset
+}
+
+// Decoder reads encoded values from an input stream
+type Decoder struct {
+	d     decoder
+	order Endianness
+}
+
+// NewDecoder creates a new Decoder reading from r with the given endianness
+func NewDecoder(r io.Reader, endianness Endianness) *Decoder {
+	return &Decoder{d: decoder{r: r}, order: endianness}
+}
+
+// Decode reads the next encoded value from the stream and stores it in the
+// value pointed by data (c.f. Unmarshal). io.EOF is returned when the end of
+// the stream is reached before the value, errors occurring within structures
+// are returned as DecodeError.
+func (dec *Decoder) Decode(data interface{}) error {
+	val, err := pointerElem(data)
+	if err != nil {
+		return err
+	}
+	start := dec.d.offset
+	dec.d.depth, dec.d.allocated = 0, 0
+	dec.d.path, dec.d.traced, dec.d.sums = dec.d.path[:0], dec.d.traced[:0], dec.d.sums[:0]
+	err = dec.d.decode(val, dec.order, &Tag{}, -1)
+	if dec.d.offset == start && errors.Is(err, io.EOF) {
+		return io.EOF
+	}
+	return err
+}
+
+// SetLimits sets the limits applying to the decoding of every value
+func (dec *Decoder) SetLimits(limits Limits) {
+	dec.d.limits = limits
+}
+
+// Offset returns the number of bytes consumed so far
+func (dec *Decoder) Offset() int64 {
+	return dec.d.offset
+}
+
+// Skip skips the next n bytes of the stream, seeking if the reader is an
+// io.Seeker and discarding them otherwise. Skipped bytes are accounted by
+// Offset.
+func (dec *Decoder) Skip(n int64) error {
+	if n < 0 {
+		return fmt.Errorf("%w: cannot skip %d bytes", ErrSeeking, n)
+	}
+	return dec.d.seek(dec.d.offset + n)
+}
@@ -0,0 +1,526 @@
+package encoding
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TagName is the name of the struct tag controlling binary layout. A tag is a
+// comma separated list of options among:
+//
+//	"-"            : field is skipped
+//	le / be        : little / big endian override for the field
+//	size=N         : fixed number of elements of a slice (units for a string),
+//	                 shorter values are zero padded
+//	len=Field      : slice or string length is taken from a previous integer
+//	                 field of the struct
+//	lenprefix=TYPE : type of the length prefix of a slice or string, among
+//	                 u8, u16, u32, u64, i8, i16, i32, i64 (default) and uleb128
+//	offset=N|Field : offset of the field from the start of the struct, either
+//	                 constant or taken from a previous integer field
+//	uleb128        : integer encoded as unsigned LEB128
+//	sleb128        : integer encoded as signed LEB128
+//	cstring        : NUL terminated string
+//	utf16          : UTF-16 string in the field endianness
+//	utf16le        : UTF-16LE string
+//	utf16be        : UTF-16BE string
+//	bits=N         : integer or bool packed on N bits with the consecutive
+//	                 bit-fields into a backing integer of the type of the
+//	                 first field of the group (a new group starts when a
+//	                 field does not fit)
+//	bitorder=ORDER : lsb (default) or msb, whether the first field of a
+//	                 bit-field group uses the least or most significant bits
+//	magic=VALUE    : expected value of an integer or byte array field, given
+//	                 in hexadecimal (0x...) or as text for byte arrays,
+//	                 Marshal writes it when the field is zero
+//	crc32=FROM:TO  : uint32 field holding the CRC-32 (IEEE) of the bytes of
+//	                 fields FROM to TO, computed by Marshal and verified by
+//	                 Unmarshal
+//
+// Varint and string options of slices, arrays and maps apply to their
+// elements (keys and values for maps) and the ones of pointers and
+// interfaces to the value they hold. Length options of maps are the ones of
+// slices, size excepted.
+//
+// Blank fields (named _) of fixed size are padding, written as zeros and
+// skipped when decoding, while other unexported fields must be skipped.
+const TagName = "bin"
+
+// TagError is returned when a bin struct tag is invalid
+type TagError struct {
+	Type  reflect.Type
+	Field string
+	Msg   string
+}
+
+// Error implements error interface
+func (e *TagError) Error() string {
+	return fmt.Sprintf("Bad %s tag on %s.%s: %s", TagName, e.Type, e.Field, e.Msg)
+}
+
+// Tag holds the parsed options of a bin struct tag (c.f. TagName)
+type Tag struct {
+	Skip     bool
+	Order    Endianness
+	Size     int
+	LenField string
+	// LenPrefix is reflect.Invalid for the default int64 prefix
+	LenPrefix reflect.Kind
+	// LenVarint is true for uleb128 length prefixes
+	LenVarint bool
+	// Offset is -1 when the field follows the previous one
+	Offset      int64
+	OffsetField string
+	Varint      Varint
+	CString     bool
+	// UTF16 is true for UTF-16 strings, UTF16Order being nil when the
+	// field endianness applies
+	UTF16      bool
+	UTF16Order Endianness
+	// Bits is the size of a bit-field, 0 for regular fields
+	Bits     int
+	BitOrder BitOrder
+	// bitOrderSet is true if bitorder is explicitly given
+	bitOrderSet bool
+	// Magic is the expected value of the field as given in the tag
+	Magic string
+	// CRC32Start and CRC32End are the first and last fields covered by the
+	// checksum held by the field
+	CRC32Start string
+	CRC32End   string
+}
+
+// BitOrder defines how bit-fields are packed into their backing integer
+type BitOrder int
+
+const (
+	// LSBFirst packs the first bit-field into the least significant bits
+	LSBFirst BitOrder = iota
+	// MSBFirst packs the first bit-field into the most significant bits
+	MSBFirst
+)
+
+// Varint identifies a variable length integer encoding
+type Varint int
+
+const (
+	// NoVarint is used for fixed size integers
+	NoVarint Varint = iota
+	// Uleb128 is the unsigned LEB128 encoding
+	Uleb128
+	// Sleb128 is the signed LEB128 encoding
+	Sleb128
+)
+
+// elem returns the tag applying to the elements of a slice or an array
+func (t *Tag) elem() *Tag {
+	return &Tag{
+		Varint:     t.Varint,
+		CString:    t.CString,
+		UTF16:      t.UTF16,
+		UTF16Order: t.UTF16Order}
+}
+
+// hasStringOpts returns true if the tag has string specific options
+func (t *Tag) hasStringOpts() bool {
+	return t.CString || t.UTF16
+}
+
+// HasLength returns true if the tag defines how to get a slice length
+func (t *Tag) HasLength() bool {
+	return t.Size > 0 || t.LenField != ""
+}
+
+var (
+	lenPrefixes = map[string]reflect.Kind{
+		"u8":  reflect.Uint8,
+		"u16": reflect.Uint16,
+		"u32": reflect.Uint32,
+		"u64": reflect.Uint64,
+		"i8":  reflect.Int8,
+		"i16": reflect.Int16,
+		"i32": reflect.Int32,
+		"i64": reflect.Int64,
+	}
+)
+
+// ParseTag parses the value of a bin struct tag
+func ParseTag(s string) (t Tag, err error) {
+	t.Offset = -1
+	if s == "" {
+		return
+	}
+	for _, opt := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(opt), "=", 2)
+		key, value := kv[0], ""
+		if len(kv) == 2 {
+			value = kv[1]
+		}
+		switch key {
+		case "-":
+			t.Skip = true
+		case "le":
+			t.Order = binary.LittleEndian
+		case "be":
+			t.Order = binary.BigEndian
+		case "size":
+			size, err := strconv.ParseInt(value, 0, 32)
+			if err != nil || size <= 0 {
+				return t, fmt.Errorf("bad size %q", value)
+			}
+			t.Size = int(size)
+		case "len":
+			if value == "" {
+				return t, fmt.Errorf("len option needs a field name")
+			}
+			t.LenField = value
+		case "lenprefix":
+			if value == "uleb128" {
+				t.LenPrefix, t.LenVarint = reflect.Uint64, true
+				continue
+			}
+			kind, ok := lenPrefixes[value]
+			if !ok {
+				return t, fmt.Errorf("unknown length prefix %q", value)
+			}
+			t.LenPrefix = kind
+		case "offset":
+			if off, err := strconv.ParseInt(value, 0, 64); err == nil {
+				if off < 0 {
+					return t, fmt.Errorf("negative offset %d", off)
+				}
+				t.Offset = off
+			} else if value != "" {
+				t.OffsetField = value
+			} else {
+				return t, fmt.Errorf("offset option needs a value")
+			}
+		case "uleb128":
+			t.Varint = Uleb128
+		case "sleb128":
+			t.Varint = Sleb128
+		case "cstring":
+			t.CString = true
+		case "utf16":
+			t.UTF16 = true
+		case "utf16le":
+			t.UTF16, t.UTF16Order = true, binary.LittleEndian
+		case "utf16be":
+			t.UTF16, t.UTF16Order = true, binary.BigEndian
+		case "bits":
+			bits, err := strconv.ParseInt(value, 0, 8)
+			if err != nil || bits <= 0 || bits > 64 {
+				return t, fmt.Errorf("bad bits %q", value)
+			}
+			t.Bits = int(bits)
+		case "bitorder":
+			switch value {
+			case "lsb":
+				t.BitOrder = LSBFirst
+			case "msb":
+				t.BitOrder = MSBFirst
+			default:
+				return t, fmt.Errorf("unknown bit order %q", value)
+			}
+			t.bitOrderSet = true
+		case "magic":
+			if value == "" {
+				return t, fmt.Errorf("magic option needs a value")
+			}
+			t.Magic = value
+		case "crc32":
+			r := strings.SplitN(value, ":", 2)
+			if len(r) != 2 || r[0] == "" || r[1] == "" {
+				return t, fmt.Errorf("crc32 option needs a Start:End field range")
+			}
+			t.CRC32Start, t.CRC32End = r[0], r[1]
+		default:
+			return t, fmt.Errorf("unknown option %q", opt)
+		}
+	}
+	if t.Size > 0 && t.LenField != "" {
+		return t, fmt.Errorf("size and len options are exclusive")
+	}
+	return
+}
+
+// field describes how a struct field is encoded
+type field struct {
+	index int
+	name  string
+	tag   Tag
+	// index of the field holding the length of this one, -1 if none
+	lenIndex int
+	// index of the field holding the offset of this one, -1 if none
+	offIndex int
+	// group is not nil if the field is the first of a bit-field group, in
+	// which case the field stands for the whole group
+	group *bitGroup
+	// magic is the expected value of the field, invalid if none
+	magic reflect.Value
+	// pad is the size of a blank padding field, -1 if the field is not
+	// padding
+	pad int
+}
+
+// bitField is a field packed into a bit-field group
+type bitField struct {
+	index int
+	name  string
+	bits  uint
+	shift uint
+}
+
+// bitGroup is a group of consecutive bit-fields packed into an integer
+type bitGroup struct {
+	// kind of the backing integer, always unsigned
+	kind   reflect.Kind
+	order  BitOrder
+	used   uint
+	fields []bitField
+}
+
+// size returns the size in bits of the backing integer
+func (g *bitGroup) size() uint {
+	return uint(primitiveSize(g.kind) * 8)
+}
+
+// add adds a field to the group and computes the shifts of the fields
+func (g *bitGroup) add(index int, name string, bits uint) {
+	g.fields = append(g.fields, bitField{index: index, name: name, bits: bits})
+	g.used += bits
+	pos := uint(0)
+	for i := range g.fields {
+		f := &g.fields[i]
+		f.shift = pos
+		if g.order == MSBFirst {
+			f.shift = g.size() - pos - f.bits
+		}
+		pos += f.bits
+	}
+}
+
+// unsignedKinds maps integer and bool kinds to the unsigned kind of the same
+// size
+var unsignedKinds = map[reflect.Kind]reflect.Kind{
+	reflect.Bool:   reflect.Uint8,
+	reflect.Int8:   reflect.Uint8,
+	reflect.Uint8:  reflect.Uint8,
+	reflect.Int16:  reflect.Uint16,
+	reflect.Uint16: reflect.Uint16,
+	reflect.Int32:  reflect.Uint32,
+	reflect.Uint32: reflect.Uint32,
+	reflect.Int64:  reflect.Uint64,
+	reflect.Uint64: reflect.Uint64,
+}
+
+// offset returns the offset of f from the start of struct v, -1 if f
+// follows the previous field
+func (f *field) offset(v reflect.Value) int64 {
+	if f.offIndex >= 0 {
+		return intValue(v.Field(f.offIndex))
+	}
+	return f.tag.Offset
+}
+
+// structSpec describes how a struct is encoded
+type structSpec struct {
+	fields    []field
+	checksums []checksum
+}
+
+var (
+	specCache sync.Map
+)
+
+func isInteger(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// isComposite returns true for the kinds of values decoded from other values
+func isComposite(k reflect.Kind) bool {
+	switch k {
+	case reflect.Struct, reflect.Array, reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+func isString(k reflect.Kind) bool {
+	return k == reflect.String
+}
+
+// hasKind returns true if typ or its elements (elements of slices, arrays
+// and pointers, keys and values of maps) are of a kind matching fn
+func hasKind(typ reflect.Type, fn func(reflect.Kind) bool) bool {
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Ptr:
+		return hasKind(typ.Elem(), fn)
+	case reflect.Map:
+		return hasKind(typ.Key(), fn) || hasKind(typ.Elem(), fn)
+	}
+	return fn(typ.Kind())
+}
+
+// fixedSize returns the encoded size of a type made of primitives and
+// arrays, -1 if its size is not fixed
+func fixedSize(typ reflect.Type) int {
+	if typ.Kind() == reflect.Array {
+		if n := fixedSize(typ.Elem()); n >= 0 {
+			return n * typ.Len()
+		}
+		return -1
+	}
+	if n := primitiveSize(typ.Kind()); n > 0 {
+		return n
+	}
+	return -1
+}
+
+// intFieldIndex returns the index of an integer field already defined
+func intFieldIndex(typ reflect.Type, names map[string]int, name string) (int, error) {
+	i, ok := names[name]
+	if !ok {
+		return -1, fmt.Errorf("field %s must be defined before", name)
+	}
+	if !isInteger(typ.Field(i).Type.Kind()) {
+		return -1, fmt.Errorf("field %s must be an integer", name)
+	}
+	return i, nil
+}
+
+// getStructSpec returns the structSpec of a struct type
+func getStructSpec(typ reflect.Type) (*structSpec, error) {
+	if spec, ok := specCache.Load(typ); ok {
+		return spec.(*structSpec), nil
+	}
+
+	spec := &structSpec{}
+	names := make(map[string]int)
+	// positions of the fields in spec.fields, bit-fields being at the
+	// position of their group
+	positions := make(map[string]int)
+	// checksum fields, resolved once all the fields are known
+	var crcs []field
+	// current bit-field group
+	var group *bitGroup
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		t, err := ParseTag(sf.Tag.Get(TagName))
+		if err != nil {
+			return nil, &TagError{typ, sf.Name, err.Error()}
+		}
+		if t.Skip {
+			continue
+		}
+		f := field{index: i, name: sf.Name, tag: t, lenIndex: -1, offIndex: -1, pad: -1}
+		kind := sf.Type.Kind()
+		if !sf.IsExported() && sf.Name != "_" {
+			return nil, &TagError{typ, sf.Name, "unexported fields must be skipped"}
+		}
+		// blank fields which are not bit-fields are padding, zeroed on encoding
+		// and skipped on decoding
+		if sf.Name == "_" && t.Bits == 0 {
+			if f.pad = fixedSize(sf.Type); f.pad < 0 {
+				return nil, &TagError{typ, sf.Name, "padding must be of fixed size"}
+			}
+			if t.Magic != "" || t.CRC32Start != "" || t.Varint != NoVarint {
+				return nil, &TagError{typ, sf.Name, "magic, crc32 and varint options do not apply to padding"}
+			}
+			spec.fields = append(spec.fields, f)
+			// a padding field ends any bit-field group
+			group = nil
+			continue
+		}
+		if (t.HasLength() || t.LenPrefix != reflect.Invalid) && kind != reflect.Slice && kind != reflect.String && kind != reflect.Map {
+			return nil, &TagError{typ, sf.Name, "length options only apply to slices, strings and maps"}
+		}
+		if t.Size > 0 && kind == reflect.Map {
+			return nil, &TagError{typ, sf.Name, "size option does not apply to maps"}
+		}
+		if t.Varint != NoVarint && !hasKind(sf.Type, isInteger) {
+			return nil, &TagError{typ, sf.Name, "varint options only apply to integers"}
+		}
+		if t.hasStringOpts() && !hasKind(sf.Type, isString) {
+			return nil, &TagError{typ, sf.Name, "string options only apply to strings"}
+		}
+		if t.CString && kind == reflect.String && (t.HasLength() || t.LenPrefix != reflect.Invalid) {
+			return nil, &TagError{typ, sf.Name, "cstring is exclusive with length options"}
+		}
+		if t.LenField != "" {
+			if f.lenIndex, err = intFieldIndex(typ, names, t.LenField); err != nil {
+				return nil, &TagError{typ, sf.Name, err.Error()}
+			}
+		}
+		if t.OffsetField != "" {
+			if f.offIndex, err = intFieldIndex(typ, names, t.OffsetField); err != nil {
+				return nil, &TagError{typ, sf.Name, err.Error()}
+			}
+		}
+		names[sf.Name] = i
+		if t.Magic != "" {
+			if t.Bits > 0 {
+				return nil, &TagError{typ, sf.Name, "magic and bits options are exclusive"}
+			}
+			if f.magic, err = parseMagic(sf.Type, t.Magic); err != nil {
+				return nil, &TagError{typ, sf.Name, err.Error()}
+			}
+		}
+		if t.CRC32Start != "" {
+			if kind != reflect.Uint32 || t.Varint != NoVarint || t.Bits > 0 || t.Magic != "" {
+				return nil, &TagError{typ, sf.Name, "crc32 option only applies to fixed size uint32"}
+			}
+			crcs = append(crcs, f)
+		}
+
+		if t.Bits > 0 {
+			ukind, ok := unsignedKinds[kind]
+			switch {
+			case !ok:
+				return nil, &TagError{typ, sf.Name, "bits option only applies to integers and bools"}
+			case t.Bits > primitiveSize(kind)*8:
+				return nil, &TagError{typ, sf.Name, fmt.Sprintf("%d bits do not fit in %s", t.Bits, sf.Type)}
+			case t.Varint != NoVarint:
+				return nil, &TagError{typ, sf.Name, "bits and varint options are exclusive"}
+			}
+			if g := group; g != nil && g.used+uint(t.Bits) <= g.size() && t.Offset < 0 && t.OffsetField == "" {
+				if t.bitOrderSet || t.Order != nil {
+					return nil, &TagError{typ, sf.Name, "bitorder and endianness must be set on the first field of a bit-field group"}
+				}
+				g.add(i, sf.Name, uint(t.Bits))
+				positions[sf.Name] = len(spec.fields) - 1
+				continue
+			}
+			group = &bitGroup{kind: ukind, order: t.BitOrder}
+			group.add(i, sf.Name, uint(t.Bits))
+			f.group = group
+		} else {
+			if t.bitOrderSet {
+				return nil, &TagError{typ, sf.Name, "bitorder option only applies to bit-fields"}
+			}
+			group = nil
+		}
+		positions[sf.Name] = len(spec.fields)
+		spec.fields = append(spec.fields, f)
+	}
+
+	for _, f := range crcs {
+		c, err := newChecksum(spec, positions, &f)
+		if err != nil {
+			return nil, &TagError{typ, f.name, err.Error()}
+		}
+		spec.checksums = append(spec.checksums, c)
+	}
+
+	specCache.Store(typ, spec)
+	return spec, nil
+}
@@ -0,0 +1,622 @@
+package encoding
+
+import (
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"unicode/utf16"
+)
+
+// decoder decodes values from a reader
+type decoder struct {
+	r io.Reader
+	// offset is the number of bytes consumed from r
+	offset  int64
+	scratch [16]byte
+	limits  Limits
+	// current nesting depth and number of bytes allocated
+	depth     int
+	allocated int64
+	// trace is called for every leaf value decoded, path being the path of
+	// the value and traced the bytes read since the last call
+	trace  TraceFunc
+	path   []string
+	traced []byte
+	// sums are the checksums being computed over the data read
+	sums []hash.Hash32
+}
+
+func newDecoder(r io.Reader) *decoder {
+	return &decoder{r: r}
+}
+
+// Read implements io.Reader interface
+func (d *decoder) Read(b []byte) (int, error) {
+	n, err := d.r.Read(b)
+	d.offset += int64(n)
+	if d.trace != nil {
+		d.traced = append(d.traced, b[:n]...)
+	}
+	for _, h := range d.sums {
+		h.Write(b[:n])
+	}
+	return n, err
+}
+
+func (d *decoder) read(b []byte) error {
+	_, err := io.ReadFull(d, b)
+	return err
+}
+
+// seek moves to offset, forward moves on non seekable readers are
+// done by discarding data
+func (d *decoder) seek(offset int64) error {
+	delta := offset - d.offset
+	if delta == 0 {
+		return nil
+	}
+	if s, ok := d.r.(io.Seeker); ok {
+		if _, err := s.Seek(delta, io.SeekCurrent); err != nil {
+			return err
+		}
+		d.offset = offset
+		return nil
+	}
+	if delta < 0 {
+		return fmt.Errorf("%w: cannot move backward to offset %d on a non seekable reader", ErrSeeking, offset)
+	}
+	n, err := io.CopyN(ioutil.Discard, d.r, delta)
+	d.offset += n
+	return err
+}
+
+// decode decodes into v, t being the tag of the field v comes from and n the
+// length of v when given by another field (-1 otherwise)
+func (d *decoder) decode(v reflect.Value, order Endianness, t *Tag, n int) error {
+	if err := d.decodeValue(v, order, t, n); err != nil {
+		return err
+	}
+	if d.trace != nil && !isComposite(v.Kind()) {
+		d.emit("", v)
+	}
+	return nil
+}
+
+// decodeValue decodes into v according to its kind
+func (d *decoder) decodeValue(v reflect.Value, order Endianness, t *Tag, n int) error {
+	if t.Order != nil {
+		order = t.Order
+	}
+
+	if t.Varint != NoVarint && isInteger(v.Kind()) {
+		return d.decodeVarint(v, t.Varint)
+	}
+
+	if isComposite(v.Kind()) {
+		defer d.leave()
+		if err := d.enter(); err != nil {
+			return err
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		// Unmarshaler implementations cannot be traced
+		if u, ok := addrInterface(v).(Unmarshaler); ok && d.trace == nil {
+			return u.DecodeBinary(d, order)
+		}
+		return d.decodeStruct(v, order)
+	case reflect.Array:
+		return d.decodeElements(v, order, t, 0)
+	case reflect.Slice:
+		return d.decodeSlice(v, order, t, n)
+	case reflect.String:
+		return d.decodeString(v, order, t, n)
+	case reflect.Map:
+		return d.decodeMap(v, order, t, n)
+	case reflect.Ptr:
+		return d.decodePointer(v, order, t, n)
+	case reflect.Interface:
+		return d.decodeInterface(v, order, t)
+	default:
+		return d.decodePrimitive(v, order)
+	}
+}
+
+func (d *decoder) decodeStruct(v reflect.Value, order Endianness) error {
+	spec, err := getStructSpec(v.Type())
+	if err != nil {
+		return err
+	}
+	start := d.offset
+	sums := make([]decodedSum, len(spec.checksums))
+	for i := range spec.fields {
+		f := &spec.fields[i]
+		if off := f.offset(v); off >= 0 {
+			if err := d.seek(start + off); err != nil {
+				return err
+			}
+		}
+		n := -1
+		if f.lenIndex >= 0 {
+			n = int(intValue(v.Field(f.lenIndex)))
+		}
+		fieldStart := d.offset
+		d.startSums(spec, sums, i)
+		if f.group != nil {
+			if err := d.decodeBits(v, f.group, fieldOrder(order, &f.tag)); err != nil {
+				return d.fieldError(err, f.name, fieldStart)
+			}
+		} else if f.pad >= 0 {
+			if _, err := d.readBytes(f.pad); err != nil {
+				return d.fieldError(err, f.name, fieldStart)
+			}
+		} else {
+			d.push(f.name)
+			if err := d.decode(v.Field(f.index), order, &f.tag, n); err != nil {
+				return d.fieldError(err, f.name, fieldStart)
+			}
+			d.pop()
+		}
+		if f.magic.IsValid() {
+			if err := checkMagic(f, v.Field(f.index)); err != nil {
+				return d.fieldError(err, f.name, fieldStart)
+			}
+		}
+		if err := d.sumFields(v, spec, sums, i, fieldStart); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeBits unpacks the fields of a bit-field group of struct v from their
+// backing integer
+func (d *decoder) decodeBits(v reflect.Value, g *bitGroup, order Endianness) error {
+	backing := reflect.New(kindTypes[g.kind]).Elem()
+	if err := d.decodePrimitive(backing, order); err != nil {
+		return err
+	}
+	packed := backing.Uint()
+	start := d.offset - int64(len(d.traced))
+	for _, bf := range g.fields {
+		fv := v.Field(bf.index)
+		if !fv.CanSet() {
+			// blank padding fields
+			continue
+		}
+		mask := uint64(1)<<bf.bits - 1
+		x := (packed >> bf.shift) & mask
+		switch {
+		case fv.Kind() == reflect.Bool:
+			fv.SetBool(x != 0)
+		case isUnsigned(fv.Kind()):
+			fv.SetUint(x)
+		default:
+			// sign extension
+			if x&(1<<(bf.bits-1)) != 0 {
+				x |= ^mask
+			}
+			fv.SetInt(int64(x))
+		}
+		// the bytes of the group are reported with its first field
+		d.traceAt(d.tracePath(bf.name), start, fv)
+	}
+	return nil
+}
+
+// fieldError wraps an error occurring while decoding the field or element
+// elem starting at offset
+func (d *decoder) fieldError(err error, elem string, offset int64) error {
+	switch fe := err.(type) {
+	case *TagError:
+		return err
+	case *DecodeError:
+		fe.Field = joinPath(elem, fe.Field)
+		return fe
+	}
+	return &DecodeError{elem, offset, err}
+}
+
+// decodeElements decodes the elements of v starting at index from
+func (d *decoder) decodeElements(v reflect.Value, order Endianness, t *Tag, from int) error {
+	if v.Type().Elem().Kind() == reflect.Uint8 && t.Varint == NoVarint {
+		if v.Kind() == reflect.Slice {
+			if err := d.read(v.Bytes()[from:]); err != nil {
+				return err
+			}
+			d.emit("", v.Slice(from, v.Len()))
+			return nil
+		}
+		for i := from; i < v.Len(); i++ {
+			if err := d.read(d.scratch[:1]); err != nil {
+				return err
+			}
+			v.Index(i).SetUint(uint64(d.scratch[0]))
+		}
+		d.emit("", v)
+		return nil
+	}
+	et := t.elem()
+	for i := from; i < v.Len(); i++ {
+		elemStart := d.offset
+		if d.trace != nil {
+			d.push(fmt.Sprintf("[%d]", i))
+		}
+		if err := d.decode(v.Index(i), order, et, -1); err != nil {
+			return d.fieldError(err, fmt.Sprintf("[%d]", i), elemStart)
+		}
+		if d.trace != nil {
+			d.pop()
+		}
+	}
+	return nil
+}
+
+// decodeLength returns the length of a slice or a string, either given by
+// the tag, by n or decoded from the length prefix
+func (d *decoder) decodeLength(order Endianness, t *Tag, n int) (int, error) {
+	switch {
+	case t.Size > 0:
+		return t.Size, nil
+	case t.LenField != "":
+		return n, nil
+	case t.LenVarint:
+		l, err := ReadUvarint(d)
+		if err != nil {
+			return 0, err
+		}
+		d.emit(" (length)", reflect.ValueOf(l))
+		if l > math.MaxInt64 {
+			return -1, nil
+		}
+		return int(l), nil
+	}
+	length := reflect.New(lenPrefixType(t)).Elem()
+	if err := d.decodePrimitive(length, order); err != nil {
+		return 0, err
+	}
+	d.emit(" (length)", length)
+	return int(intValue(length)), nil
+}
+
+func (d *decoder) decodeSlice(v reflect.Value, order Endianness, t *Tag, n int) error {
+	length, err := d.decodeLength(order, t, n)
+	if err != nil {
+		return err
+	}
+	elemSize := int(v.Type().Elem().Size())
+	if err := d.checkLength(length, elemSize); err != nil {
+		return err
+	}
+	// the slice grows by chunks as elements are decoded
+	chunk := CapHint(length, elemSize)
+	if chunk == 0 && length > 0 {
+		chunk = 1
+	}
+	v.Set(reflect.MakeSlice(v.Type(), 0, chunk))
+	for done := 0; done < length; done = v.Len() {
+		grow := length - done
+		if grow > chunk {
+			grow = chunk
+		}
+		v.Set(reflect.AppendSlice(v, reflect.MakeSlice(v.Type(), grow, grow)))
+		if err := d.decodeElements(v, order, t, done); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) decodeMap(v reflect.Value, order Endianness, t *Tag, n int) error {
+	length, err := d.decodeLength(order, t, n)
+	if err != nil {
+		return err
+	}
+	typ := v.Type()
+	entrySize := int(typ.Key().Size() + typ.Elem().Size())
+	if err := d.checkLength(length, entrySize); err != nil {
+		return err
+	}
+	m := reflect.MakeMapWithSize(typ, CapHint(length, entrySize))
+	et := t.elem()
+	for i := 0; i < length; i++ {
+		start := d.offset
+		key := reflect.New(typ.Key()).Elem()
+		if d.trace != nil {
+			d.push(fmt.Sprintf("[%d] (key)", i))
+		}
+		if err := d.decode(key, order, et, -1); err != nil {
+			return d.fieldError(err, fmt.Sprintf("[%d]", i), start)
+		}
+		start = d.offset
+		value := reflect.New(typ.Elem()).Elem()
+		if d.trace != nil {
+			d.pop()
+			d.push(fmt.Sprintf("[%v]", key))
+		}
+		if err := d.decode(value, order, et, -1); err != nil {
+			return d.fieldError(err, fmt.Sprintf("[%v]", key), start)
+		}
+		if d.trace != nil {
+			d.pop()
+		}
+		m.SetMapIndex(key, value)
+	}
+	v.Set(m)
+	return nil
+}
+
+func (d *decoder) decodePointer(v reflect.Value, order Endianness, t *Tag, n int) error {
+	if err := d.read(d.scratch[:1]); err != nil {
+		return err
+	}
+	d.emit(" (present)", reflect.ValueOf(d.scratch[0] == 1))
+	switch d.scratch[0] {
+	case 0:
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	case 1:
+	default:
+		return fmt.Errorf("%w: %d", ErrInvalidPresence, d.scratch[0])
+	}
+	if v.IsNil() {
+		if err := d.checkLength(1, int(v.Type().Elem().Size())); err != nil {
+			return err
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+	}
+	return d.decode(v.Elem(), order, t, n)
+}
+
+func (d *decoder) decodeInterface(v reflect.Value, order Endianness, t *Tag) error {
+	id, err := ReadUvarint(d)
+	if err != nil {
+		return err
+	}
+	if id == 0 {
+		d.emit(" (type)", reflect.ValueOf("nil"))
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	typ, ok := typeByID(id)
+	if !ok {
+		return fmt.Errorf("%w: id %d", ErrUnknownType, id)
+	}
+	d.emit(" (type)", reflect.ValueOf(typ.String()))
+	if !typ.AssignableTo(v.Type()) {
+		return fmt.Errorf("%w: %s with id %d does not implement %s", ErrUnknownType, typ, id, v.Type())
+	}
+	if err := d.checkLength(1, int(typ.Size())); err != nil {
+		return err
+	}
+	value := reflect.New(typ).Elem()
+	if err := d.decode(value, order, t, -1); err != nil {
+		return err
+	}
+	v.Set(value)
+	return nil
+}
+
+func (d *decoder) decodeString(v reflect.Value, order Endianness, t *Tag, n int) error {
+	var data []byte
+	unit := 1
+	if t.UTF16 {
+		unit = 2
+		if t.UTF16Order != nil {
+			order = t.UTF16Order
+		}
+	}
+
+	if t.CString {
+		var err error
+		if data, err = d.readTerminated(unit); err != nil {
+			return err
+		}
+	} else {
+		length, err := d.decodeLength(order, t, n)
+		if err != nil {
+			return err
+		}
+		if length > math.MaxInt32 && unit > 1 {
+			return fmt.Errorf("%w: string of %d units", ErrLengthOverflow, length)
+		}
+		if err := d.checkLength(length, unit); err != nil {
+			return err
+		}
+		if data, err = d.readBytes(length * unit); err != nil {
+			return err
+		}
+		if t.Size > 0 {
+			// fixed size strings are NUL padded
+			data = data[:terminator(data, unit)]
+		}
+	}
+
+	if t.UTF16 {
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[2*i:])
+		}
+		v.SetString(string(utf16.Decode(units)))
+		return nil
+	}
+	v.SetString(string(data))
+	return nil
+}
+
+// terminator returns the offset of the first NUL unit in data, len(data) if
+// not found
+func terminator(data []byte, unit int) int {
+	for i := 0; i+unit <= len(data); i += unit {
+		nul := true
+		for _, c := range data[i : i+unit] {
+			nul = nul && c == 0
+		}
+		if nul {
+			return i
+		}
+	}
+	return len(data)
+}
+
+// readTerminated reads units of data until a NUL unit, the returned data
+// does not contain the terminator
+func (d *decoder) readTerminated(unit int) (data []byte, err error) {
+	b := d.scratch[:unit]
+	for {
+		if err = d.read(b); err != nil {
+			return
+		}
+		if terminator(b, unit) == 0 {
+			return data, d.checkLength(len(data)/unit, unit)
+		}
+		data = append(data, b...)
+		if max := d.limits.MaxSliceLen; max > 0 && len(data)/unit > max {
+			return nil, &LimitError{"MaxSliceLen", int64(len(data) / unit), int64(max)}
+		}
+		if max := d.limits.MaxAlloc; max > 0 && int64(len(data)) > max-d.allocated {
+			return nil, &LimitError{"MaxAlloc", d.allocated + int64(len(data)), max}
+		}
+	}
+}
+
+// ReadUvarint reads an unsigned LEB128 integer from r
+func ReadUvarint(r io.Reader) (x uint64, err error) {
+	var b [1]byte
+	var shift uint
+	for i := 0; ; i++ {
+		if _, err = io.ReadFull(r, b[:]); err != nil {
+			return
+		}
+		if (i == 9 && b[0] > 1) || i > 9 {
+			return 0, fmt.Errorf("%w: uleb128 larger than 64 bits", ErrVarintOverflow)
+		}
+		x |= uint64(b[0]&0x7f) << shift
+		if b[0] < 0x80 {
+			return
+		}
+		shift += 7
+	}
+}
+
+// ReadVarint reads a signed LEB128 integer from r
+func ReadVarint(r io.Reader) (x int64, err error) {
+	var b [1]byte
+	var shift uint
+	for i := 0; ; i++ {
+		if _, err = io.ReadFull(r, b[:]); err != nil {
+			return
+		}
+		if i > 9 {
+			return 0, fmt.Errorf("%w: sleb128 larger than 64 bits", ErrVarintOverflow)
+		}
+		x |= int64(b[0]&0x7f) << shift
+		shift += 7
+		if b[0] < 0x80 {
+			// sign extension
+			if shift < 64 && b[0]&0x40 != 0 {
+				x |= -1 << shift
+			}
+			return
+		}
+	}
+}
+
+// decodeVarint decodes a LEB128 variable length integer into v
+func (d *decoder) decodeVarint(v reflect.Value, kind Varint) error {
+	if kind == Uleb128 {
+		x, err := ReadUvarint(d)
+		switch {
+		case err != nil:
+			return err
+		case isUnsigned(v.Kind()) && !v.OverflowUint(x):
+			v.SetUint(x)
+			return nil
+		case !isUnsigned(v.Kind()) && x <= math.MaxInt64 && !v.OverflowInt(int64(x)):
+			v.SetInt(int64(x))
+			return nil
+		}
+		return fmt.Errorf("%w: %d does not fit in %s", ErrVarintOverflow, x, v.Type())
+	}
+
+	x, err := ReadVarint(d)
+	switch {
+	case err != nil:
+		return err
+	case isUnsigned(v.Kind()) && x >= 0 && !v.OverflowUint(uint64(x)):
+		v.SetUint(uint64(x))
+		return nil
+	case !isUnsigned(v.Kind()) && !v.OverflowInt(x):
+		v.SetInt(x)
+		return nil
+	}
+	return fmt.Errorf("%w: %d does not fit in %s", ErrVarintOverflow, x, v.Type())
+}
+
+func (d *decoder) decodePrimitive(v reflect.Value, order Endianness) error {
+	size := primitiveSize(v.Kind())
+	if size == 0 {
+		return &UnsupportedTypeError{v.Type()}
+	}
+	b := d.scratch[:size]
+	if err := d.read(b); err != nil {
+		return err
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(b[0] != 0)
+	case reflect.Int8:
+		v.SetInt(int64(int8(b[0])))
+	case reflect.Uint8:
+		v.SetUint(uint64(b[0]))
+	case reflect.Int16:
+		v.SetInt(int64(int16(order.Uint16(b))))
+	case reflect.Uint16:
+		v.SetUint(uint64(order.Uint16(b)))
+	case reflect.Int32:
+		v.SetInt(int64(int32(order.Uint32(b))))
+	case reflect.Uint32:
+		v.SetUint(uint64(order.Uint32(b)))
+	case reflect.Int64:
+		v.SetInt(int64(order.Uint64(b)))
+	case reflect.Uint64:
+		v.SetUint(order.Uint64(b))
+	case reflect.Float32:
+		v.SetFloat(float64(math.Float32frombits(order.Uint32(b))))
+	case reflect.Float64:
+		v.SetFloat(math.Float64frombits(order.Uint64(b)))
+	case reflect.Complex64:
+		v.SetComplex(complex(
+			float64(math.Float32frombits(order.Uint32(b))),
+			float64(math.Float32frombits(order.Uint32(b[4:]))),
+		))
+	case reflect.Complex128:
+		v.SetComplex(complex(
+			math.Float64frombits(order.Uint64(b)),
+			math.Float64frombits(order.Uint64(b[8:])),
+		))
+	}
+	return nil
+}
+
+// primitiveSize returns the encoded size of a primitive kind, 0 if kind is
+// not a primitive
+func primitiveSize(k reflect.Kind) int {
+	switch k {
+	case reflect.Bool, reflect.Int8, reflect.Uint8:
+		return 1
+	case reflect.Int16, reflect.Uint16:
+		return 2
+	case reflect.Int32, reflect.Uint32, reflect.Float32:
+		return 4
+	case reflect.Int64, reflect.Uint64, reflect.Float64, reflect.Complex64:
+		return 8
+	case reflect.Complex128:
+		return 16
+	}
+	return 0
+}
@@ -1,6 +1,6 @@
 module github.com/0xrawsec/golang-utils
 
-go 1.13
+go 1.23
 
 require (
 	github.com/kr/fs v0.1.0 // indirect